@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"fishtankhunt/api/server/scoreboard"
+)
+
+// configEnv names the environment variable that points at a config file when
+// -config is not given.
+const configEnv = "SCOREBOARD_CONFIG"
+
+// envProfileEnv names the environment variable selecting the profile.
+const envProfileEnv = "SCOREBOARD_ENV"
+
+// setting ties one config field to its flag and environment variable.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value func(c *scoreboard.Config) flag.Value
+}
+
+var settings = []setting{
+	{"env", envProfileEnv, "profile providing the defaults: dev or prod (empty for none)",
+		func(c *scoreboard.Config) flag.Value { return (*stringValue)(&c.Env) }},
+	{"addr", "SCOREBOARD_ADDR", "listen address",
+		func(c *scoreboard.Config) flag.Value { return (*stringValue)(&c.Addr) }},
+	{"storage", "SCOREBOARD_STORAGE", "where scores live: file or memory (lost on exit)",
+		func(c *scoreboard.Config) flag.Value { return (*stringValue)(&c.Storage) }},
+	{"data-path", "SCOREBOARD_DATA_PATH", "scores file, relative to the working directory",
+		func(c *scoreboard.Config) flag.Value { return (*stringValue)(&c.DataPath) }},
+	{"format", "SCOREBOARD_FORMAT", "on-disk scores format: json, gzip or binary (existing files are detected on load)",
+		func(c *scoreboard.Config) flag.Value { return (*stringValue)(&c.Format) }},
+	{"cors-origins", "SCOREBOARD_CORS_ORIGINS", "comma-separated origins allowed to call the API",
+		func(c *scoreboard.Config) flag.Value { return (*listValue)(&c.CORSOrigins) }},
+	{"default-page-size", "SCOREBOARD_DEFAULT_PAGE_SIZE", "page size used when GET /scores omits size",
+		func(c *scoreboard.Config) flag.Value { return (*intValue)(&c.DefaultPageSize) }},
+	{"max-page-size", "SCOREBOARD_MAX_PAGE_SIZE", "largest size GET /scores accepts; bigger pages get 400",
+		func(c *scoreboard.Config) flag.Value { return (*intValue)(&c.MaxPageSize) }},
+	{"max-scores", "SCOREBOARD_MAX_SCORES", "hold at most this many scores in memory and read the rest from a rank index on disk (0 holds all)",
+		func(c *scoreboard.Config) flag.Value { return (*intValue)(&c.MaxScores) }},
+	{"readonly", "SCOREBOARD_READONLY", "serve GET requests only; submissions get 403 and the scores file is never written",
+		func(c *scoreboard.Config) flag.Value { return (*boolValue)(&c.ReadOnly) }},
+	{"admin-token", "SCOREBOARD_ADMIN_TOKEN", "bearer token for the /admin API (empty disables it)",
+		func(c *scoreboard.Config) flag.Value { return (*stringValue)(&c.AdminToken) }},
+	{"seed-scores", "SCOREBOARD_SEED_SCORES", "add this many demo scores at startup if the default board is empty",
+		func(c *scoreboard.Config) flag.Value { return (*intValue)(&c.SeedScores) }},
+	{"verbose", "SCOREBOARD_VERBOSE", "log status, size, origin and client address of every request",
+		func(c *scoreboard.Config) flag.Value { return (*boolValue)(&c.Verbose) }},
+	{"persist-interval", "SCOREBOARD_PERSIST_INTERVAL", "coalesce score writes and persist at most once per interval (0 writes on every submission)",
+		func(c *scoreboard.Config) flag.Value { return &c.PersistInterval }},
+	{"scores-cache-ttl", "SCOREBOARD_SCORES_CACHE_TTL", "cache identical GET /scores pages for this long, invalidated on writes (0 disables)",
+		func(c *scoreboard.Config) flag.Value { return &c.ScoresCacheTTL }},
+	{"read-timeout", "SCOREBOARD_READ_TIMEOUT", "HTTP read timeout",
+		func(c *scoreboard.Config) flag.Value { return &c.ReadTimeout }},
+	{"read-header-timeout", "SCOREBOARD_READ_HEADER_TIMEOUT", "HTTP read header timeout",
+		func(c *scoreboard.Config) flag.Value { return &c.ReadHeaderTimeout }},
+	{"write-timeout", "SCOREBOARD_WRITE_TIMEOUT", "HTTP write timeout",
+		func(c *scoreboard.Config) flag.Value { return &c.WriteTimeout }},
+	{"idle-timeout", "SCOREBOARD_IDLE_TIMEOUT", "HTTP keep-alive idle timeout",
+		func(c *scoreboard.Config) flag.Value { return &c.IdleTimeout }},
+	{"shutdown-timeout", "SCOREBOARD_SHUTDOWN_TIMEOUT", "time allowed for in-flight requests on shutdown",
+		func(c *scoreboard.Config) flag.Value { return &c.ShutdownTimeout }},
+}
+
+// loadConfig registers the config flags on fs, parses args and returns the
+// configuration layered as profile defaults <- config file <- environment <-
+// flags. The profile itself is picked with the same precedence.
+// It also registers -config and -print-config; the latter prints the
+// resolved values and exits.
+func loadConfig(fs *flag.FlagSet, args []string) (scoreboard.Config, error) {
+	// Flags are parsed into a scratch config and only applied for the ones
+	// actually given, so they override the file and environment.
+	fromFlags := scoreboard.DefaultConfig()
+	for _, st := range settings {
+		fs.Var(st.value(&fromFlags), st.flag, fmt.Sprintf("%s (env %s)", st.usage, st.env))
+	}
+	configPath := fs.String("config", "", "JSON config file (env "+configEnv+")")
+	printConfig := fs.Bool("print-config", false, "print the resolved configuration and exit")
+	if err := fs.Parse(args); err != nil {
+		return scoreboard.Config{}, err
+	}
+
+	path := *configPath
+	if path == "" {
+		path = os.Getenv(configEnv)
+	}
+	var file []byte
+	var profile struct {
+		Env        string `json:"env"`
+		SeedScores *int   `json:"seedScores"`
+	}
+	if path != "" {
+		var err error
+		if file, err = os.ReadFile(path); err != nil {
+			return scoreboard.Config{}, fmt.Errorf("open config: %w", err)
+		}
+		if err := json.Unmarshal(file, &profile); err != nil {
+			return scoreboard.Config{}, fmt.Errorf("decode config %s: %w", path, err)
+		}
+	}
+	if env, ok := os.LookupEnv(envProfileEnv); ok {
+		profile.Env = env
+	}
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "env" {
+			profile.Env = f.Value.String()
+		}
+	})
+
+	cfg, err := scoreboard.ProfileConfig(profile.Env)
+	if err != nil {
+		return scoreboard.Config{}, err
+	}
+	if file != nil {
+		if err := decodeConfigFile(&cfg, path, file); err != nil {
+			return scoreboard.Config{}, err
+		}
+	}
+
+	// seedSet records whether seedScores was given explicitly rather than
+	// taken from the profile.
+	seedSet := profile.SeedScores != nil
+	for _, st := range settings {
+		raw, ok := os.LookupEnv(st.env)
+		if !ok {
+			continue
+		}
+		seedSet = seedSet || st.flag == "seed-scores"
+		if err := st.value(&cfg).Set(raw); err != nil {
+			return scoreboard.Config{}, fmt.Errorf("invalid %s %q: %w", st.env, raw, err)
+		}
+	}
+
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		for _, st := range settings {
+			if st.flag == f.Name && flagErr == nil {
+				flagErr = st.value(&cfg).Set(f.Value.String())
+				seedSet = seedSet || st.flag == "seed-scores"
+			}
+		}
+	})
+	if flagErr != nil {
+		return scoreboard.Config{}, flagErr
+	}
+	// An explicit readOnly wins over the dev profile's demo scores. Only a
+	// seedScores set by the user is reported as a conflict by Validate.
+	if cfg.ReadOnly && !seedSet {
+		cfg.SeedScores = 0
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return scoreboard.Config{}, err
+	}
+
+	if *printConfig {
+		out, err := json.MarshalIndent(redactSecrets(cfg), "", "  ")
+		if err != nil {
+			return scoreboard.Config{}, err
+		}
+		fmt.Println(string(out))
+		os.Exit(0)
+	}
+	return cfg, nil
+}
+
+// redacted stands in for secrets in -print-config output, which often ends up in
+// terminals, CI logs and bug reports. Empty secrets stay empty so the output
+// still shows whether one is set.
+const redacted = "REDACTED"
+
+func redactSecrets(cfg scoreboard.Config) scoreboard.Config {
+	if cfg.AdminToken != "" {
+		cfg.AdminToken = redacted
+	}
+	// Copy the tenants so the caller's config keeps its keys.
+	cfg.Tenants = append([]scoreboard.Tenant(nil), cfg.Tenants...)
+	for i := range cfg.Tenants {
+		if cfg.Tenants[i].Key != "" {
+			cfg.Tenants[i].Key = redacted
+		}
+	}
+	return cfg
+}
+
+func decodeConfigFile(c *scoreboard.Config, path string, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return fmt.Errorf("decode config %s: %w", path, err)
+	}
+	return nil
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error { *v = stringValue(s); return nil }
+func (v *stringValue) String() string     { return string(*v) }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*v = intValue(n)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+// boolValue is a flag that may be given bare, as in -readonly.
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*v = boolValue(b)
+	return nil
+}
+
+func (v *boolValue) String() string   { return strconv.FormatBool(bool(*v)) }
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+// listValue is a comma-separated list; an empty string clears it.
+type listValue []string
+
+func (v *listValue) Set(s string) error {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	*v = items
+	return nil
+}
+
+func (v *listValue) String() string { return strings.Join(*v, ",") }
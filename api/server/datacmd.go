@@ -0,0 +1,125 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"time"
+
+	"fishtankhunt/api/server/scoreboard"
+)
+
+// openBoard loads the scores file described by the config flags on fs.
+func openBoard(fs *flag.FlagSet, args []string) *scoreboard.Server {
+	sb, err := scoreboard.NewServer(loadDataConfig(fs, args))
+	if err != nil {
+		log.Fatalf("failed to load scores: %v", err)
+	}
+	return sb
+}
+
+// loadDataConfig loads the configuration of a command that works on the
+// scores file. It refuses memory storage, the dev profile's default, which
+// would give the command an empty board and drop its changes on exit. Demo
+// seeding is turned off so the command sees the file as it is.
+func loadDataConfig(fs *flag.FlagSet, args []string) scoreboard.Config {
+	cfg, err := loadConfig(fs, args)
+	if err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	if cfg.Storage == scoreboard.StorageMemory {
+		log.Fatalf("%s works on the scores file, but storage is %s; pass -storage %s", fs.Name(), cfg.Storage, scoreboard.StorageFile)
+	}
+	cfg.SeedScores = 0
+	return cfg
+}
+
+func closeBoard(sb *scoreboard.Server) {
+	if err := sb.Close(); err != nil {
+		log.Fatalf("failed to write scores: %v", err)
+	}
+}
+
+func runMigrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	sb := openBoard(fs, args)
+	defer closeBoard(sb)
+
+	if err := sb.Rewrite(); err != nil {
+		log.Fatalf("failed to rewrite scores: %v", err)
+	}
+	cfg := sb.Config()
+	fmt.Printf("rewrote %d scores in %s as %s\n", len(sb.Scores()), cfg.DataPath, cfg.Format)
+}
+
+func runPrune(args []string) {
+	fs := flag.NewFlagSet("prune", flag.ExitOnError)
+	olderThan := fs.Duration("older-than", 0, "delete scores submitted longer ago than this")
+	below := fs.Int("below", 0, "delete scores lower than this value")
+	keepTop := fs.Int("keep-top", 0, "delete everything ranked below this position")
+	dryRun := fs.Bool("dry-run", false, "report what would be deleted without writing")
+	sb := openBoard(fs, args)
+	defer closeBoard(sb)
+
+	if *olderThan <= 0 && *below <= 0 && *keepTop <= 0 {
+		log.Fatalf("prune needs at least one of -older-than, -below or -keep-top")
+	}
+
+	ranked := sb.Scores()
+	top := make(map[int]bool, *keepTop)
+	for i := 0; i < *keepTop && i < len(ranked); i++ {
+		top[ranked[i].ID] = true
+	}
+	cutoff := time.Now().Add(-*olderThan)
+	drop := func(sc scoreboard.Score) bool {
+		switch {
+		case *olderThan > 0 && sc.CreatedAt.Before(cutoff):
+			return true
+		case *below > 0 && sc.Score < *below:
+			return true
+		case *keepTop > 0 && !top[sc.ID]:
+			return true
+		}
+		return false
+	}
+
+	if *dryRun {
+		matched := 0
+		for _, sc := range ranked {
+			if drop(sc) {
+				matched++
+			}
+		}
+		fmt.Printf("would delete %d of %d scores\n", matched, len(ranked))
+		return
+	}
+
+	removed, err := sb.RemoveScores(drop)
+	if err != nil {
+		log.Fatalf("failed to prune scores: %v", err)
+	}
+	fmt.Printf("deleted %d of %d scores\n", removed, len(ranked))
+}
+
+func runSeed(args []string) {
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	count := fs.Int("n", 50, "number of scores to add")
+	seed := fs.Int64("seed", 0, "random seed (0 picks one from the clock)")
+	sb := openBoard(fs, args)
+	defer closeBoard(sb)
+
+	if *count <= 0 {
+		log.Fatalf("-n must be positive")
+	}
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(*seed))
+
+	added, err := sb.AddScores(scoreboard.RandomSubmissions(rng, *count))
+	if err != nil {
+		log.Fatalf("failed to seed scores: %v", err)
+	}
+	fmt.Printf("added %d scores (seed %d)\n", len(added), *seed)
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fishtankhunt/api/server/scoreboard"
+)
+
+// signingKeyEnv names the environment variable holding the optional HMAC key
+// used to sign export manifests.
+const signingKeyEnv = "SCOREBOARD_SIGNING_KEY"
+
+// artifactManifest is written next to every export so the file can be checked
+// for truncation or tampering before it is restored.
+type artifactManifest struct {
+	File       string    `json:"file"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	HMACSHA256 string    `json:"hmacSha256,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+func manifestPath(artifactPath string) string {
+	return artifactPath + ".manifest.json"
+}
+
+func signingKey() []byte {
+	return []byte(os.Getenv(signingKeyEnv))
+}
+
+// writeArtifact atomically writes the output of write to path and records
+// its checksum (and HMAC signature when key is non-empty) in the sidecar
+// manifest. The content is hashed as it is written, so it is never held in
+// memory as a whole.
+func writeArtifact(path string, key []byte, write func(w io.Writer) error) (artifactManifest, error) {
+	digest := sha256.New()
+	var mac hash.Hash
+	if len(key) > 0 {
+		mac = hmac.New(sha256.New, key)
+	}
+	var size int64
+	err := writeFileAtomic(path, func(f io.Writer) error {
+		writers := []io.Writer{f, digest}
+		if mac != nil {
+			writers = append(writers, mac)
+		}
+		cw := &countingWriter{w: io.MultiWriter(writers...)}
+		if err := write(cw); err != nil {
+			return err
+		}
+		size = cw.n
+		return nil
+	})
+	if err != nil {
+		return artifactManifest{}, err
+	}
+
+	manifest := artifactManifest{
+		File:      filepath.Base(path),
+		Size:      size,
+		SHA256:    hex.EncodeToString(digest.Sum(nil)),
+		CreatedAt: time.Now().UTC(),
+	}
+	if mac != nil {
+		manifest.HMACSHA256 = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return artifactManifest{}, err
+	}
+	err = writeFileAtomic(manifestPath(path), func(w io.Writer) error {
+		_, err := w.Write(append(encoded, '\n'))
+		return err
+	})
+	if err != nil {
+		return artifactManifest{}, err
+	}
+	return manifest, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// verifyArtifact checks path against its manifest. A signed manifest requires
+// key, and a non-empty key requires the manifest to be signed.
+func verifyArtifact(path, manifestFile string, key []byte) (artifactManifest, error) {
+	raw, err := os.ReadFile(manifestFile)
+	if err != nil {
+		return artifactManifest{}, fmt.Errorf("read manifest: %w", err)
+	}
+	var manifest artifactManifest
+	if err := json.Unmarshal(raw, &manifest); err != nil {
+		return artifactManifest{}, fmt.Errorf("decode manifest: %w", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return manifest, err
+	}
+	defer f.Close()
+
+	digest := sha256.New()
+	writers := []io.Writer{digest}
+	var mac hash.Hash
+	if len(key) > 0 {
+		mac = hmac.New(sha256.New, key)
+		writers = append(writers, mac)
+	}
+	size, err := io.Copy(io.MultiWriter(writers...), f)
+	if err != nil {
+		return manifest, err
+	}
+
+	if size != manifest.Size {
+		return manifest, fmt.Errorf("size mismatch: manifest has %d bytes, file has %d", manifest.Size, size)
+	}
+	if got := hex.EncodeToString(digest.Sum(nil)); got != manifest.SHA256 {
+		return manifest, errors.New("sha256 mismatch: file contents do not match manifest")
+	}
+
+	switch {
+	case mac == nil && manifest.HMACSHA256 != "":
+		return manifest, fmt.Errorf("manifest is signed but %s is not set", signingKeyEnv)
+	case mac != nil && manifest.HMACSHA256 == "":
+		return manifest, errors.New("manifest is not signed")
+	case mac != nil:
+		want, err := hex.DecodeString(manifest.HMACSHA256)
+		if err != nil || !hmac.Equal(mac.Sum(nil), want) {
+			return manifest, errors.New("signature mismatch")
+		}
+	}
+	return manifest, nil
+}
+
+// writeFileAtomic writes path through a buffered temp file in the same
+// directory and renames it into place once write succeeds.
+func writeFileAtomic(path string, write func(w io.Writer) error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	bw := bufio.NewWriter(tmp)
+	if err := write(bw); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// writeScoresJSON writes the scores passed to emit as an indented JSON
+// array, one record at a time, producing the same bytes as
+// json.MarshalIndent plus a newline. It reports how many it wrote.
+func writeScoresJSON(w io.Writer, each func(emit func(scoreboard.Score) error) error) (int, error) {
+	n := 0
+	err := each(func(sc scoreboard.Score) error {
+		item, err := json.MarshalIndent(sc, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if n == 0 {
+			sep = "[\n  "
+		}
+		n++
+		_, err = fmt.Fprintf(w, "%s%s", sep, item)
+		return err
+	})
+	if err != nil {
+		return n, err
+	}
+	end := "\n]\n"
+	if n == 0 {
+		end = "[]\n"
+	}
+	_, err = io.WriteString(w, end)
+	return n, err
+}
+
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	out := fs.String("o", "", "output file (default scores-export-<timestamp>.json)")
+	cfg := loadDataConfig(fs, args)
+
+	path := *out
+	if path == "" {
+		path = fmt.Sprintf("scores-export-%s.json", time.Now().UTC().Format("20060102T150405Z"))
+	}
+
+	sb, err := scoreboard.NewServer(cfg)
+	if err != nil {
+		log.Fatalf("failed to load scores: %v", err)
+	}
+	var exported int
+	manifest, err := writeArtifact(path, signingKey(), func(w io.Writer) error {
+		var err error
+		exported, err = writeScoresJSON(w, sb.EachScore)
+		return err
+	})
+	if err != nil {
+		log.Fatalf("failed to write export: %v", err)
+	}
+	if err := sb.Close(); err != nil {
+		log.Fatalf("failed to close scores: %v", err)
+	}
+
+	signed := "unsigned"
+	if manifest.HMACSHA256 != "" {
+		signed = "signed"
+	}
+	fmt.Printf("exported %d scores to %s (sha256 %s, %s)\n", exported, path, manifest.SHA256, signed)
+}
+
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	manifestFile := fs.String("manifest", "", "manifest file (default <file>.manifest.json)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: verify [-manifest file] <artifact>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	path := fs.Arg(0)
+	if *manifestFile == "" {
+		*manifestFile = manifestPath(path)
+	}
+	manifest, err := verifyArtifact(path, *manifestFile, signingKey())
+	if err != nil {
+		log.Fatalf("verify %s: %v", path, err)
+	}
+	fmt.Printf("%s: OK (%d bytes, sha256 %s)\n", path, manifest.Size, manifest.SHA256)
+}
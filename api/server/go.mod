@@ -1,3 +1,3 @@
 module fishtankhunt/api/server
 
-go 1.21
+go 1.22
@@ -0,0 +1,108 @@
+package scoreboard
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// adminHandler serves the admin routes, which report and switch the data
+// file of a board. They require the configured admin token as a bearer token
+// and is disabled when no token is configured, except in the dev profile,
+// where it is open.
+type adminHandler struct {
+	server *Server
+	token  string
+	open   bool
+	// dataDir is the directory swap targets are resolved against.
+	dataDir string
+	logger  *log.Logger
+}
+
+type swapRequest struct {
+	Board string `json:"board"`
+	// File is relative to the directory of the configured data path.
+	File   string `json:"file"`
+	Create bool   `json:"create"`
+}
+
+type dataFileResponse struct {
+	Board    string `json:"board"`
+	DataPath string `json:"dataPath"`
+	Scores   int    `json:"scores"`
+}
+
+func (h *adminHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if h.token == "" && h.open {
+		return true
+	}
+	if h.token == "" {
+		http.Error(w, "admin API is disabled: set adminToken to enable it", http.StatusForbidden)
+		return false
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(h.token)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="scoreboard admin"`)
+		http.Error(w, "missing or invalid admin token", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (h *adminHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	board := r.URL.Query().Get("board")
+	store := h.server.store
+	if board != "" {
+		var ok bool
+		if store, ok = h.server.boards[board]; !ok {
+			http.Error(w, "unknown board", http.StatusNotFound)
+			return
+		}
+	}
+	writeJSON(h.logger, w, http.StatusOK, dataFileResponse{
+		Board:    board,
+		DataPath: store.dataPath(),
+		Scores:   store.Page(1, 1).TotalItems,
+	})
+}
+
+func (h *adminHandler) handleSwap(w http.ResponseWriter, r *http.Request) {
+	body := http.MaxBytesReader(w, r.Body, 1<<20)
+	defer body.Close()
+
+	var req swapRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+	if !filepath.IsLocal(req.File) {
+		http.Error(w, "file must be a relative path inside the data directory", http.StatusBadRequest)
+		return
+	}
+
+	path := filepath.Join(h.dataDir, req.File)
+	total, err := h.server.SwapDataFile(req.Board, path, req.Create)
+	switch {
+	case errors.Is(err, ErrReadOnly):
+		http.Error(w, "scoreboard is read-only: data files cannot be switched", http.StatusForbidden)
+		return
+	case errors.Is(err, errUnknownBoard):
+		http.Error(w, "unknown board", http.StatusNotFound)
+		return
+	case errors.Is(err, errDataFileActive):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case errors.Is(err, errInvalidDataFile):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	case err != nil:
+		h.logger.Printf("failed to swap data file: %v", err)
+		http.Error(w, "failed to swap data file", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(h.logger, w, http.StatusOK, dataFileResponse{Board: req.Board, DataPath: path, Scores: total})
+}
@@ -0,0 +1,69 @@
+package scoreboard
+
+import (
+	"sync"
+	"time"
+)
+
+// maxCachedPages bounds the response cache; clients only ever poll a handful
+// of distinct pages, so hitting this means someone is walking the whole board.
+const maxCachedPages = 256
+
+type pageKey struct {
+	page, size int
+}
+
+type cachedPage struct {
+	body     []byte
+	revision uint64
+	expires  time.Time
+}
+
+// responseCache is a short-lived cache of encoded GET /scores pages. Entries
+// expire after ttl or as soon as the store revision moves on.
+type responseCache struct {
+	ttl     time.Duration
+	clock   Clock
+	mu      sync.Mutex
+	entries map[pageKey]cachedPage
+}
+
+func newResponseCache(ttl time.Duration, clock Clock) *responseCache {
+	return &responseCache{
+		ttl:     ttl,
+		clock:   clock,
+		entries: make(map[pageKey]cachedPage),
+	}
+}
+
+func (c *responseCache) get(key pageKey, revision uint64) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.revision != revision || c.clock.Now().After(entry.expires) {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.body, true
+}
+
+// put stores body, which must not be modified afterwards.
+func (c *responseCache) put(key pageKey, revision uint64, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= maxCachedPages {
+		now := c.clock.Now()
+		for k, entry := range c.entries {
+			if entry.revision != revision || now.After(entry.expires) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= maxCachedPages {
+			return
+		}
+	}
+	c.entries[key] = cachedPage{body: body, revision: revision, expires: c.clock.Now().Add(c.ttl)}
+}
@@ -0,0 +1,191 @@
+package scoreboard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// Profile names accepted by ProfileConfig and Config.Env.
+const (
+	EnvDev  = "dev"
+	EnvProd = "prod"
+)
+
+// Storage backends selectable with Config.Storage.
+const (
+	StorageFile   = "file"
+	StorageMemory = "memory"
+)
+
+// minProdAdminToken is the shortest admin token accepted in prod.
+const minProdAdminToken = 16
+
+// Config holds every tunable of the scoreboard server.
+type Config struct {
+	Env               string   `json:"env,omitempty"`
+	Addr              string   `json:"addr"`
+	Storage           string   `json:"storage"`
+	DataPath          string   `json:"dataPath"`
+	Format            string   `json:"format"`
+	CORSOrigins       []string `json:"corsOrigins"`
+	DefaultPageSize   int      `json:"defaultPageSize"`
+	MaxPageSize       int      `json:"maxPageSize"`
+	MaxScores         int      `json:"maxScores"`
+	ReadOnly          bool     `json:"readOnly"`
+	AdminToken        string   `json:"adminToken,omitempty"`
+	SeedScores        int      `json:"seedScores,omitempty"`
+	Verbose           bool     `json:"verbose,omitempty"`
+	PersistInterval   Duration `json:"persistInterval"`
+	ScoresCacheTTL    Duration `json:"scoresCacheTTL"`
+	ReadTimeout       Duration `json:"readTimeout"`
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"`
+	WriteTimeout      Duration `json:"writeTimeout"`
+	IdleTimeout       Duration `json:"idleTimeout"`
+	ShutdownTimeout   Duration `json:"shutdownTimeout"`
+	Tenants           []Tenant `json:"tenants,omitempty"`
+}
+
+// DefaultConfig returns the configuration used when nothing is overridden.
+func DefaultConfig() Config {
+	return Config{
+		Addr:     ":8090",
+		Storage:  StorageFile,
+		DataPath: "data/scores.json",
+		Format:   FormatJSON,
+		CORSOrigins: []string{
+			"http://localhost:8080",
+			"http://localhost:8000",
+			"http://127.0.0.1:8080",
+			"http://127.0.0.1:8000",
+		},
+		DefaultPageSize:   5,
+		MaxPageSize:       1000,
+		ReadTimeout:       Duration(5 * time.Second),
+		ReadHeaderTimeout: Duration(5 * time.Second),
+		WriteTimeout:      Duration(5 * time.Second),
+		IdleTimeout:       Duration(60 * time.Second),
+		ShutdownTimeout:   Duration(5 * time.Second),
+	}
+}
+
+// ProfileConfig returns the defaults of the named environment profile; ""
+// means DefaultConfig.
+//
+// dev suits local work: any origin may call the API, every request is
+// logged in detail, scores live in memory, the board starts with demo scores
+// and the admin API needs no token (the serve command then listens on
+// loopback only). prod is for hosted servers: CORS origins
+// must be listed explicitly, the admin API needs a strong token, and every
+// submission is written to disk before it is acknowledged.
+func ProfileConfig(env string) (Config, error) {
+	c := DefaultConfig()
+	switch env {
+	case "":
+	case EnvDev:
+		c.Env = EnvDev
+		c.Storage = StorageMemory
+		c.CORSOrigins = []string{"*"}
+		c.SeedScores = 50
+		c.Verbose = true
+	case EnvProd:
+		c.Env = EnvProd
+		c.CORSOrigins = nil
+		c.PersistInterval = 0
+	default:
+		return Config{}, fmt.Errorf("unknown env %q (want %s or %s)", env, EnvDev, EnvProd)
+	}
+	return c, nil
+}
+
+// Validate reports the first invalid setting in c.
+func (c Config) Validate() error {
+	switch {
+	case c.Env != "" && c.Env != EnvDev && c.Env != EnvProd:
+		return fmt.Errorf("unknown env %q (want %s or %s)", c.Env, EnvDev, EnvProd)
+	case c.Storage != StorageFile && c.Storage != StorageMemory:
+		return fmt.Errorf("unknown storage %q (want %s or %s)", c.Storage, StorageFile, StorageMemory)
+	case c.Addr == "":
+		return errors.New("addr must not be empty")
+	case c.DataPath == "":
+		return errors.New("dataPath must not be empty")
+	case !validFormat(c.Format):
+		return fmt.Errorf("unknown scores format %q", c.Format)
+	case c.DefaultPageSize <= 0:
+		return errors.New("defaultPageSize must be positive")
+	case c.MaxPageSize < c.DefaultPageSize:
+		return errors.New("maxPageSize must be at least defaultPageSize")
+	case c.MaxScores < 0:
+		return errors.New("maxScores must not be negative")
+	case c.MaxScores > 0 && c.Storage == StorageMemory:
+		return errors.New("maxScores keeps the scores beyond the cap on disk, so it needs file storage")
+	case c.SeedScores < 0:
+		return errors.New("seedScores must not be negative")
+	case c.SeedScores > 0 && c.ReadOnly:
+		return errors.New("seedScores would add demo scores to a readOnly board; set it to 0")
+	}
+	if c.Env == EnvProd {
+		if err := c.validateProd(); err != nil {
+			return fmt.Errorf("prod: %w", err)
+		}
+	}
+	return validateTenants(c.Tenants)
+}
+
+func (c Config) validateProd() error {
+	switch {
+	case c.Storage != StorageFile:
+		return errors.New("storage must be file")
+	case len(c.CORSOrigins) == 0:
+		return errors.New("corsOrigins must list the origins allowed to call the API")
+	case slices.Contains(c.CORSOrigins, "*"):
+		return errors.New("corsOrigins must not contain *")
+	case c.AdminToken != "" && len(c.AdminToken) < minProdAdminToken:
+		return fmt.Errorf("adminToken must be at least %d characters", minProdAdminToken)
+	case c.SeedScores > 0:
+		return errors.New("seedScores would add demo scores to the live board")
+	case c.PersistInterval > 0:
+		return errors.New("persistInterval must be 0 so every submission is on disk before it is acknowledged")
+	}
+	return nil
+}
+
+func (c Config) storeOptions(logger *log.Logger) storeOptions {
+	return storeOptions{
+		persistInterval: time.Duration(c.PersistInterval),
+		format:          c.Format,
+		maxScores:       c.MaxScores,
+		readOnly:        c.ReadOnly,
+		logger:          logger,
+	}
+}
+
+// Duration is a time.Duration that reads and writes strings such as "1.5s",
+// both in JSON and as a flag.Value.
+type Duration time.Duration
+
+func (d *Duration) Set(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d *Duration) String() string { return time.Duration(*d).String() }
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string such as \"5s\": %w", err)
+	}
+	return d.Set(s)
+}
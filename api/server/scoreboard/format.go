@@ -0,0 +1,218 @@
+package scoreboard
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// On-disk formats for the scores file. Loading detects the format from the
+// file contents, so changing Config.Format only affects the next write.
+const (
+	FormatJSON   = "json"
+	FormatGzip   = "gzip"
+	FormatBinary = "binary"
+)
+
+// binaryMagic prefixes gob-encoded score files so they can be told apart from
+// JSON and gzip on load.
+var binaryMagic = []byte("FTHSCORES1\n")
+
+var gzipMagic = []byte{0x1f, 0x8b}
+
+var errEmptyScoresFile = errors.New("scores file is empty")
+
+func validFormat(format string) bool {
+	switch format {
+	case FormatJSON, FormatGzip, FormatBinary:
+		return true
+	}
+	return false
+}
+
+// encodeScores writes records in format. JSON output is written one record
+// at a time so large boards never need a second full copy in memory.
+func encodeScores(w io.Writer, format string, records []Score) error {
+	sw, err := newScoresWriter(w, format)
+	if err != nil {
+		return err
+	}
+	for _, rec := range records {
+		if err := sw.add(rec); err != nil {
+			sw.abort()
+			return err
+		}
+	}
+	return sw.close()
+}
+
+// scoresWriter encodes a scores file one record at a time. Its output
+// matches a json.Encoder (indented with two spaces for FormatJSON). The
+// binary format is a single gob value, so it still collects every record
+// and encodes them on close.
+type scoresWriter struct {
+	format string
+	bw     *bufio.Writer
+	zw     *gzip.Writer
+	n      int
+	// binary holds the records of a FormatBinary file until close.
+	binary []Score
+}
+
+func newScoresWriter(w io.Writer, format string) (*scoresWriter, error) {
+	sw := &scoresWriter{format: format}
+	switch format {
+	case FormatJSON, "":
+		sw.format = FormatJSON
+		sw.bw = bufio.NewWriter(w)
+	case FormatGzip:
+		sw.zw = gzip.NewWriter(w)
+		sw.bw = bufio.NewWriter(sw.zw)
+	case FormatBinary:
+		sw.bw = bufio.NewWriter(w)
+		sw.binary = []Score{}
+	default:
+		return nil, fmt.Errorf("unknown scores format %q", format)
+	}
+	return sw, nil
+}
+
+func (sw *scoresWriter) add(rec Score) error {
+	if sw.format == FormatBinary {
+		sw.binary = append(sw.binary, rec)
+		return nil
+	}
+	open, sep := "[", ","
+	if sw.format == FormatJSON {
+		open, sep = "[\n  ", ",\n  "
+	}
+	if sw.n == 0 {
+		sw.bw.WriteString(open)
+	} else {
+		sw.bw.WriteString(sep)
+	}
+	sw.n++
+	var data []byte
+	var err error
+	if sw.format == FormatJSON {
+		data, err = json.MarshalIndent(rec, "  ", "  ")
+	} else {
+		data, err = json.Marshal(rec)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = sw.bw.Write(data)
+	return err
+}
+
+func (sw *scoresWriter) close() error {
+	switch {
+	case sw.format == FormatBinary:
+		if _, err := sw.bw.Write(binaryMagic); err != nil {
+			return err
+		}
+		if err := gob.NewEncoder(sw.bw).Encode(sw.binary); err != nil {
+			return err
+		}
+	case sw.n == 0:
+		sw.bw.WriteString("[]\n")
+	case sw.format == FormatJSON:
+		sw.bw.WriteString("\n]\n")
+	default:
+		sw.bw.WriteString("]\n")
+	}
+	if err := sw.bw.Flush(); err != nil {
+		sw.abort()
+		return err
+	}
+	if sw.zw != nil {
+		return sw.zw.Close()
+	}
+	return nil
+}
+
+// abort releases the writer after a failed add.
+func (sw *scoresWriter) abort() {
+	if sw.zw != nil {
+		sw.zw.Close()
+	}
+}
+
+// decodeScores detects the format of r and returns the records it holds
+// along with the detected format. JSON records are decoded one at a time
+// rather than reading the whole file first. Files holding only whitespace
+// return errEmptyScoresFile.
+func decodeScores(r io.Reader) ([]Score, string, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	if err := skipSpace(br); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, FormatJSON, errEmptyScoresFile
+		}
+		return nil, FormatJSON, err
+	}
+
+	if head, _ := br.Peek(len(gzipMagic)); bytes.Equal(head, gzipMagic) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, FormatGzip, err
+		}
+		defer zr.Close()
+		stored, err := decodeJSONArray(zr)
+		return stored, FormatGzip, err
+	}
+
+	if head, _ := br.Peek(len(binaryMagic)); bytes.Equal(head, binaryMagic) {
+		br.Discard(len(binaryMagic))
+		var stored []Score
+		if err := gob.NewDecoder(br).Decode(&stored); err != nil {
+			return nil, FormatBinary, err
+		}
+		return stored, FormatBinary, nil
+	}
+
+	stored, err := decodeJSONArray(br)
+	return stored, FormatJSON, err
+}
+
+func decodeJSONArray(r io.Reader) ([]Score, error) {
+	dec := json.NewDecoder(r)
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, fmt.Errorf("expected JSON array, found %v", tok)
+	}
+	var stored []Score
+	for dec.More() {
+		var sc Score
+		if err := dec.Decode(&sc); err != nil {
+			return nil, err
+		}
+		stored = append(stored, sc)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+func skipSpace(br *bufio.Reader) error {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return br.UnreadByte()
+	}
+}
@@ -0,0 +1,359 @@
+package scoreboard
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type scoreHandler struct {
+	store           Store
+	clock           Clock
+	logger          *log.Logger
+	allowedOrigins  []string
+	defaultPageSize int
+	maxPageSize     int
+	// readOnly rejects submissions with 403 while reads keep working.
+	readOnly bool
+	hooks    hooks
+	// cache holds encoded GET /scores pages; nil disables it.
+	cache *responseCache
+}
+
+type postScoreRequest struct {
+	Name        string `json:"name"`
+	Score       int    `json:"score"`
+	TimeSeconds int    `json:"timeSeconds"`
+}
+
+// scoreResponse describes one score with its standing, as returned by
+// POST /scores and GET /scores/{id}.
+type scoreResponse struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Score       int    `json:"score"`
+	TimeSeconds int    `json:"timeSeconds"`
+	Rank        int    `json:"rank"`
+	Percentile  int    `json:"percentile"`
+}
+
+type scoreListItem struct {
+	ID          int    `json:"id"`
+	Name        string `json:"name"`
+	Score       int    `json:"score"`
+	TimeSeconds int    `json:"timeSeconds"`
+	Rank        int    `json:"rank"`
+}
+
+type scoresResponse struct {
+	Items      []scoreListItem `json:"items"`
+	Page       int             `json:"page"`
+	Size       int             `json:"size"`
+	TotalItems int             `json:"totalItems"`
+	TotalPages int             `json:"totalPages"`
+}
+
+func (h *scoreHandler) handlePreflight(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *scoreHandler) handlePost(w http.ResponseWriter, r *http.Request) {
+	if h.readOnly {
+		http.Error(w, readOnlyMessage, http.StatusForbidden)
+		return
+	}
+	body := http.MaxBytesReader(w, r.Body, 1<<20)
+	defer body.Close()
+
+	var req postScoreRequest
+	if err := json.NewDecoder(body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON payload", http.StatusBadRequest)
+		return
+	}
+
+	req.Name = sanitizeName(req.Name)
+	if req.Score < 0 || req.TimeSeconds < 0 {
+		http.Error(w, "score and timeSeconds must be non-negative", http.StatusBadRequest)
+		return
+	}
+
+	sub := Submission{Name: req.Name, Score: req.Score, TimeSeconds: req.TimeSeconds}
+	for _, hook := range h.hooks.submission {
+		if err := hook(r, &sub); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	// Hooks may rewrite the submission, so the built-in rules apply again.
+	sub.Name = sanitizeName(sub.Name)
+	if sub.Score < 0 || sub.TimeSeconds < 0 {
+		http.Error(w, "score and timeSeconds must be non-negative", http.StatusBadRequest)
+		return
+	}
+
+	placed, err := h.store.Add(sub, h.clock.Now().UTC())
+	if err != nil {
+		h.logger.Printf("failed to persist score: %v", err)
+		http.Error(w, "failed to save score", http.StatusInternalServerError)
+		return
+	}
+	entry := placed.Score
+	h.logger.Printf("saved score: name=%s, score=%d, timeSeconds=%d, id=%d, rank=%d", entry.Name, entry.Score, entry.TimeSeconds, entry.ID, placed.Rank)
+
+	writeJSON(h.logger, w, http.StatusCreated, newScoreResponse(placed))
+}
+
+func (h *scoreHandler) handleGetByID(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "invalid score id", http.StatusBadRequest)
+		return
+	}
+	placed, ok := h.store.Get(id)
+	if !ok {
+		http.Error(w, "score not found", http.StatusNotFound)
+		return
+	}
+	writeJSON(h.logger, w, http.StatusOK, newScoreResponse(placed))
+}
+
+func newScoreResponse(placed Placement) scoreResponse {
+	return scoreResponse{
+		ID:          placed.Score.ID,
+		Name:        placed.Score.Name,
+		Score:       placed.Score.Score,
+		TimeSeconds: placed.Score.TimeSeconds,
+		Rank:        placed.Rank,
+		Percentile:  computePercentile(placed.Rank, placed.Total),
+	}
+}
+
+func (h *scoreHandler) handleGet(w http.ResponseWriter, r *http.Request) {
+	page, err := parseIntDefault(r.URL.Query().Get("page"), 1)
+	if err != nil {
+		http.Error(w, "invalid page parameter", http.StatusBadRequest)
+		return
+	}
+
+	size, err := parseIntDefault(r.URL.Query().Get("size"), h.defaultPageSize)
+	if err != nil {
+		http.Error(w, "invalid size parameter", http.StatusBadRequest)
+		return
+	}
+	if size <= 0 {
+		size = h.defaultPageSize
+	}
+	if size > h.maxPageSize {
+		http.Error(w, fmt.Sprintf("size must be at most %d", h.maxPageSize), http.StatusBadRequest)
+		return
+	}
+
+	if size > streamThreshold {
+		h.streamPage(w, r, page, size)
+		return
+	}
+
+	if h.cache == nil {
+		writeJSON(h.logger, w, http.StatusOK, h.pageResponse(r, page, size))
+		return
+	}
+
+	key := pageKey{page: page, size: size}
+	// Read the revision before building the page: if a write lands in
+	// between, the entry is stale on arrival rather than wrongly fresh.
+	revision := h.store.Revision()
+	if body, ok := h.cache.get(key, revision); ok {
+		w.Header().Set("X-Cache", "HIT")
+		writeJSONBody(h.logger, w, http.StatusOK, body)
+		return
+	}
+
+	body, err := json.Marshal(h.pageResponse(r, page, size))
+	if err != nil {
+		h.logger.Printf("error encoding response: %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+	body = append(body, '\n')
+	h.cache.put(key, revision, body)
+	w.Header().Set("X-Cache", "MISS")
+	writeJSONBody(h.logger, w, http.StatusOK, body)
+}
+
+// listPage reads one page and passes it through the OnListed hooks.
+func (h *scoreHandler) listPage(r *http.Request, page, size int) ScorePage {
+	p := h.store.Page(page, size)
+	for _, hook := range h.hooks.listed {
+		hook(r, &p)
+	}
+	return p
+}
+
+func (h *scoreHandler) pageResponse(r *http.Request, page, size int) scoresResponse {
+	p := h.listPage(r, page, size)
+	items := make([]scoreListItem, len(p.Items))
+	for i, entry := range p.Items {
+		items[i] = newScoreListItem(entry, p.FirstRank+i)
+	}
+	return scoresResponse{
+		Items:      items,
+		Page:       p.Page,
+		Size:       size,
+		TotalItems: p.TotalItems,
+		TotalPages: p.TotalPages,
+	}
+}
+
+func newScoreListItem(entry Score, rank int) scoreListItem {
+	return scoreListItem{
+		ID:          entry.ID,
+		Name:        entry.Name,
+		Score:       entry.Score,
+		TimeSeconds: entry.TimeSeconds,
+		Rank:        rank,
+	}
+}
+
+func computePercentile(rank, total int) int {
+	if total <= 0 || rank <= 0 {
+		return 0
+	}
+	return ((rank - 1) * 100) / total
+}
+
+func parseIntDefault(value string, def int) (int, error) {
+	if strings.TrimSpace(value) == "" {
+		return def, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, err
+	}
+	return parsed, nil
+}
+
+func sanitizeName(raw string) string {
+	name := strings.TrimSpace(raw)
+	if name == "" {
+		return "Anon"
+	}
+	if len(name) > 32 {
+		return name[:32]
+	}
+	return name
+}
+
+func setCORSHeaders(w http.ResponseWriter, r *http.Request, allowedOrigins []string, readOnly bool) {
+	origin := r.Header.Get("Origin")
+
+	// Check if the origin is in the allowed list; "*" allows any origin
+	for _, allowed := range allowedOrigins {
+		if origin != "" && (origin == allowed || allowed == "*") {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			break
+		}
+	}
+
+	if readOnly {
+		w.Header().Set("Access-Control-Allow-Methods", "GET,OPTIONS")
+	} else {
+		w.Header().Set("Access-Control-Allow-Methods", "GET,POST,OPTIONS")
+	}
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, "+apiKeyHeader)
+	w.Header().Set("Vary", "Origin")
+}
+
+const (
+	// initialBufferSize fits a default five-item page with room to spare.
+	initialBufferSize = 1 << 10
+	// maxPooledBufferSize keeps one oversized response from pinning memory.
+	maxPooledBufferSize = 64 << 10
+)
+
+var bufferPool = sync.Pool{
+	New: func() any {
+		return bytes.NewBuffer(make([]byte, 0, initialBufferSize))
+	},
+}
+
+func writeJSON(logger *log.Logger, w http.ResponseWriter, status int, v any) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer func() {
+		if buf.Cap() <= maxPooledBufferSize {
+			bufferPool.Put(buf)
+		}
+	}()
+
+	if err := json.NewEncoder(buf).Encode(v); err != nil {
+		logger.Printf("error encoding response: %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSONBody(logger, w, status, buf.Bytes())
+}
+
+func writeJSONBody(logger *log.Logger, w http.ResponseWriter, status int, body []byte) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	if _, err := w.Write(body); err != nil {
+		logger.Printf("error writing response: %v", err)
+	}
+}
+
+// loggingMiddleware logs one line per request, labelled with the route name.
+// In verbose mode the line also carries the status, response size, origin and
+// client address.
+func loggingMiddleware(logger *log.Logger, verbose bool, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		if !verbose {
+			next.ServeHTTP(w, r)
+			logger.Printf("%s %s [%s] %s", r.Method, r.URL.Path, routeLabelOrDash(r), time.Since(start))
+			return
+		}
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+		logger.Printf("%s %s [%s] %d %dB %s origin=%q remote=%s", r.Method, r.URL.RequestURI(),
+			routeLabelOrDash(r), rec.status, rec.size, time.Since(start), r.Header.Get("Origin"), r.RemoteAddr)
+	})
+}
+
+func routeLabelOrDash(r *http.Request) string {
+	if name := RouteName(r.Context()); name != "" {
+		return name
+	}
+	return "-"
+}
+
+// responseRecorder captures the status and size of a response for logging.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseWriter.Write(b)
+	r.size += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, which the
+// streaming path needs to flush.
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,67 @@
+package scoreboard
+
+import (
+	"context"
+	"net/http"
+)
+
+// SubmissionHook runs on every POST /scores after the built-in checks and
+// before the score is stored. It may change sub, for example to normalise
+// names, or reject it by returning an error, whose text is sent to the client
+// with 400 Bad Request.
+type SubmissionHook func(r *http.Request, sub *Submission) error
+
+// ListedHook runs on every GET /scores after the page is read and before it
+// is encoded. It may change the page. With ScoresCacheTTL set, the result is
+// cached and served to other clients, so it should not depend on who asked.
+type ListedHook func(r *http.Request, page *ScorePage)
+
+// Middleware wraps an http.Handler.
+type Middleware func(http.Handler) http.Handler
+
+type hooks struct {
+	submission []SubmissionHook
+	listed     []ListedHook
+	pre, post  []Middleware
+}
+
+// OnSubmission registers hook to run on submissions. Hooks run in the order
+// they were registered and the first error wins.
+func OnSubmission(hook SubmissionHook) Option {
+	return func(o *options) { o.hooks.submission = append(o.hooks.submission, hook) }
+}
+
+// OnListed registers hook to run on listed pages, in registration order.
+func OnListed(hook ListedHook) Option {
+	return func(o *options) { o.hooks.listed = append(o.hooks.listed, hook) }
+}
+
+// WithPreMiddleware wraps the whole API in mw, so it sees every request
+// before logging, CORS, API key checks and rate limits. The first middleware
+// given is the outermost.
+func WithPreMiddleware(mw ...Middleware) Option {
+	return func(o *options) { o.hooks.pre = append(o.hooks.pre, mw...) }
+}
+
+// WithPostMiddleware wraps each board's score handler in mw, so it only sees
+// requests that passed the API key and rate limit checks; use BoardName to
+// tell boards apart. The first middleware given is the outermost.
+func WithPostMiddleware(mw ...Middleware) Option {
+	return func(o *options) { o.hooks.post = append(o.hooks.post, mw...) }
+}
+
+func chain(h http.Handler, mw []Middleware) http.Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+type boardKey struct{}
+
+// BoardName returns the tenant whose board ctx's request is served from, or
+// "" for the default board. It is meant for hooks and post middleware.
+func BoardName(ctx context.Context) string {
+	name, _ := ctx.Value(boardKey{}).(string)
+	return name
+}
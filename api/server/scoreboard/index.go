@@ -0,0 +1,497 @@
+package scoreboard
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync/atomic"
+)
+
+// A rank index lets a board hold more scores than its memory cap. It is
+// written next to the scores file as <file>.idx and holds every score as one
+// JSON line in rank order, followed by a table of record offsets, so any
+// rank can be read with two ReadAt calls, and a table of (ID, rank) pairs
+// sorted by ID, so a score is found by binary search:
+//
+//	header | records | offsets (count+1 int64s) | IDs (count pairs of int64s)
+//
+// All integers are little-endian.
+//
+// The header records the size and modification time of the scores file the
+// index was written with. An index that no longer matches is ignored and
+// rebuilt from the scores file.
+
+var indexMagic = [8]byte{'F', 'T', 'H', 'I', 'D', 'X', '2', '\n'}
+
+var errStaleIndex = errors.New("rank index does not match the scores file")
+
+type indexHeader struct {
+	Magic     [8]byte
+	Count     int64
+	MaxID     int64
+	DataSize  int64
+	DataMod   int64
+	OffsetsAt int64
+	IDsAt     int64
+}
+
+var indexHeaderSize = int64(binary.Size(indexHeader{}))
+
+func indexPath(dataPath string) string {
+	return dataPath + ".idx"
+}
+
+// scoreIndex is an open rank index. Its methods are safe for concurrent use
+// until the last reference is closed.
+type scoreIndex struct {
+	f         *os.File
+	count     int
+	maxID     int
+	offsetsAt int64
+	idsAt     int64
+	// refs counts the opener plus every reader that took a reference with
+	// acquire, so a store can replace the index while a read is still
+	// using the old one.
+	refs atomic.Int32
+}
+
+// openScoreIndex opens the index at path and checks that it was written for
+// data, the current state of the scores file.
+func openScoreIndex(path string, data os.FileInfo) (*scoreIndex, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var h indexHeader
+	if err := binary.Read(io.NewSectionReader(f, 0, indexHeaderSize), binary.LittleEndian, &h); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("read rank index header: %w", err)
+	}
+	if h.Magic != indexMagic || h.DataSize != data.Size() || h.DataMod != data.ModTime().UnixNano() {
+		f.Close()
+		return nil, errStaleIndex
+	}
+	ix := &scoreIndex{f: f, count: int(h.Count), maxID: int(h.MaxID), offsetsAt: h.OffsetsAt, idsAt: h.IDsAt}
+	ix.refs.Store(1)
+	return ix, nil
+}
+
+func (ix *scoreIndex) acquire() {
+	ix.refs.Add(1)
+}
+
+// Close drops one reference and closes the file with the last one.
+func (ix *scoreIndex) Close() error {
+	if ix.refs.Add(-1) > 0 {
+		return nil
+	}
+	return ix.f.Close()
+}
+
+// offsets returns the file offsets of records [start, end]; the last one is
+// where record end would begin.
+func (ix *scoreIndex) offsets(start, end int) ([]int64, error) {
+	raw := make([]byte, (end-start+1)*8)
+	if _, err := ix.f.ReadAt(raw, ix.offsetsAt+int64(start)*8); err != nil {
+		return nil, fmt.Errorf("read rank index offsets: %w", err)
+	}
+	offs := make([]int64, end-start+1)
+	for i := range offs {
+		offs[i] = int64(binary.LittleEndian.Uint64(raw[i*8:]))
+	}
+	return offs, nil
+}
+
+// read returns the scores ranked [start, end), counting from zero.
+func (ix *scoreIndex) read(start, end int) ([]Score, error) {
+	if start >= end {
+		return nil, nil
+	}
+	offs, err := ix.offsets(start, end)
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, offs[len(offs)-1]-offs[0])
+	if _, err := ix.f.ReadAt(buf, offs[0]); err != nil {
+		return nil, fmt.Errorf("read rank index records: %w", err)
+	}
+	scores := make([]Score, end-start)
+	for i := range scores {
+		line := buf[offs[i]-offs[0] : offs[i+1]-offs[0]]
+		if err := json.Unmarshal(line, &scores[i]); err != nil {
+			return nil, fmt.Errorf("decode rank index record %d: %w", start+i, err)
+		}
+	}
+	return scores, nil
+}
+
+// each calls fn with the scores ranked start and below, in order.
+func (ix *scoreIndex) each(start int, fn func(Score) error) error {
+	if start >= ix.count {
+		return nil
+	}
+	offs, err := ix.offsets(start, start)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bufio.NewReaderSize(io.NewSectionReader(ix.f, offs[0], ix.offsetsAt-offs[0]), 64<<10))
+	for i := start; i < ix.count; i++ {
+		var sc Score
+		if err := dec.Decode(&sc); err != nil {
+			return fmt.Errorf("decode rank index record %d: %w", i, err)
+		}
+		if err := fn(sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookup returns the rank of the score with id, searching the ID table.
+func (ix *scoreIndex) lookup(id int) (int, bool, error) {
+	var err error
+	pair := func(i int) (int, int) {
+		raw := make([]byte, 16)
+		if _, rerr := ix.f.ReadAt(raw, ix.idsAt+int64(i)*16); rerr != nil {
+			err = fmt.Errorf("read rank index IDs: %w", rerr)
+			return 0, 0
+		}
+		return int(binary.LittleEndian.Uint64(raw)), int(binary.LittleEndian.Uint64(raw[8:]))
+	}
+	i := sort.Search(ix.count, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		got, _ := pair(i)
+		return err != nil || got >= id
+	})
+	if err != nil || i == ix.count {
+		return 0, false, err
+	}
+	got, rank := pair(i)
+	if err != nil || got != id {
+		return 0, false, err
+	}
+	return rank, true, nil
+}
+
+// countAbove returns how many of the scores ranked start and below rank
+// above entry, by binary search.
+func (ix *scoreIndex) countAbove(start int, entry Score) (int, error) {
+	var err error
+	n := sort.Search(ix.count-start, func(i int) bool {
+		if err != nil {
+			return true
+		}
+		var rec []Score
+		rec, err = ix.read(start+i, start+i+1)
+		return err != nil || !RanksAbove(rec[0], entry)
+	})
+	return n, err
+}
+
+// indexWriter writes a new rank index to a temp file. Records must be added
+// in rank order.
+type indexWriter struct {
+	f       *os.File
+	bw      *bufio.Writer
+	offsets []int64
+	// ids[i] is the ID of record i.
+	ids   []int64
+	pos   int64
+	maxID int
+}
+
+// createIndex starts an index in dir, or in the system temp directory when
+// dir is "".
+func createIndex(dir string) (*indexWriter, error) {
+	f, err := os.CreateTemp(dir, "scores-*.idx.tmp")
+	if err != nil {
+		return nil, err
+	}
+	w := &indexWriter{f: f, bw: bufio.NewWriterSize(f, 64<<10), pos: indexHeaderSize}
+	if _, err := w.bw.Write(make([]byte, indexHeaderSize)); err != nil {
+		w.abort()
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *indexWriter) add(sc Score) error {
+	data, err := json.Marshal(sc)
+	if err != nil {
+		return err
+	}
+	w.offsets = append(w.offsets, w.pos)
+	w.ids = append(w.ids, int64(sc.ID))
+	w.bw.Write(data)
+	if err := w.bw.WriteByte('\n'); err != nil {
+		return err
+	}
+	w.pos += int64(len(data)) + 1
+	if sc.ID > w.maxID {
+		w.maxID = sc.ID
+	}
+	return nil
+}
+
+// finish completes the index for the scores file described by data and
+// renames it to path.
+func (w *indexWriter) finish(path string, data os.FileInfo) error {
+	h := indexHeader{
+		Magic:     indexMagic,
+		Count:     int64(len(w.offsets)),
+		MaxID:     int64(w.maxID),
+		DataSize:  data.Size(),
+		DataMod:   data.ModTime().UnixNano(),
+		OffsetsAt: w.pos,
+		IDsAt:     w.pos + int64(len(w.offsets)+1)*8,
+	}
+	raw := make([]byte, 16)
+	for _, off := range append(w.offsets, w.pos) {
+		binary.LittleEndian.PutUint64(raw, uint64(off))
+		if _, err := w.bw.Write(raw[:8]); err != nil {
+			w.abort()
+			return err
+		}
+	}
+	byID := make([]int32, len(w.ids))
+	for i := range byID {
+		byID[i] = int32(i)
+	}
+	sort.SliceStable(byID, func(i, j int) bool { return w.ids[byID[i]] < w.ids[byID[j]] })
+	for _, rank := range byID {
+		binary.LittleEndian.PutUint64(raw, uint64(w.ids[rank]))
+		binary.LittleEndian.PutUint64(raw[8:], uint64(rank))
+		if _, err := w.bw.Write(raw); err != nil {
+			w.abort()
+			return err
+		}
+	}
+	if err := w.bw.Flush(); err != nil {
+		w.abort()
+		return err
+	}
+	var head bytes.Buffer
+	binary.Write(&head, binary.LittleEndian, h)
+	if _, err := w.f.WriteAt(head.Bytes(), 0); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		w.abort()
+		return err
+	}
+	if err := w.f.Close(); err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+	if err := os.Rename(w.f.Name(), path); err != nil {
+		os.Remove(w.f.Name())
+		return err
+	}
+	return nil
+}
+
+func (w *indexWriter) abort() {
+	w.f.Close()
+	os.Remove(w.f.Name())
+}
+
+// coldTail holds the scores of a capped board that rank below the entries
+// kept in memory. Every score in memory ranks above every score in the tail.
+// The tail is index[skip:], which never changes once written, merged with
+// pending: entries evicted from memory since the index was written.
+type coldTail struct {
+	// index is nil until the tail is first written out.
+	index *scoreIndex
+	// skip counts the leading index records that were in memory when the
+	// index was written; they are not part of the tail.
+	skip int
+	// diskTop is index record skip, cached for placing new entries.
+	diskTop Score
+	// pending is in rank order and is replaced rather than modified, like
+	// scoreStore.scores.
+	pending []Score
+}
+
+func (c *coldTail) diskLen() int {
+	if c.index == nil {
+		return 0
+	}
+	return c.index.count - c.skip
+}
+
+func (c *coldTail) len() int {
+	return c.diskLen() + len(c.pending)
+}
+
+// ranksAboveTail reports whether entry ranks above every score in the tail.
+func (c *coldTail) ranksAboveTail(entry Score) bool {
+	if len(c.pending) > 0 && !RanksAbove(entry, c.pending[0]) {
+		return false
+	}
+	return c.diskLen() == 0 || RanksAbove(entry, c.diskTop)
+}
+
+// diskAbove counts the scores in the index part of the tail that rank above
+// entry.
+func (c *coldTail) diskAbove(entry Score) (int, error) {
+	if c.diskLen() == 0 {
+		return 0, nil
+	}
+	return c.index.countAbove(c.skip, entry)
+}
+
+// insert adds entry to pending and returns its position in the tail.
+func (c *coldTail) insert(entry Score) (int, error) {
+	above, err := c.diskAbove(entry)
+	if err != nil {
+		return 0, err
+	}
+
+	idx := sort.Search(len(c.pending), func(i int) bool {
+		return RanksAbove(entry, c.pending[i])
+	})
+	pending := make([]Score, len(c.pending)+1)
+	copy(pending, c.pending[:idx])
+	pending[idx] = entry
+	copy(pending[idx+1:], c.pending[idx:])
+	c.pending = pending
+	return above + idx, nil
+}
+
+// slice returns tail entries [start, end), merging the index with pending.
+func (c *coldTail) slice(start, end int) ([]Score, error) {
+	// Find how many pending entries precede position start: the first t for
+	// which pending[t] does not rank above disk entry start-t-1.
+	disk, pending := c.diskLen(), c.pending
+	lo := max(0, start-disk)
+	hi := min(start, len(pending))
+	var err error
+	t := lo + sort.Search(hi-lo, func(i int) bool {
+		t := lo + i
+		if err != nil {
+			return true
+		}
+		var rec []Score
+		rec, err = c.index.read(c.skip+start-t-1, c.skip+start-t)
+		return err != nil || !RanksAbove(pending[t], rec[0])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	d := start - t
+	fromDisk, err := c.readDisk(d, min(disk, d+end-start))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]Score, 0, end-start)
+	for len(items) < end-start {
+		if t < len(pending) && (len(fromDisk) == 0 || RanksAbove(pending[t], fromDisk[0])) {
+			items = append(items, pending[t])
+			t++
+			continue
+		}
+		items = append(items, fromDisk[0])
+		fromDisk = fromDisk[1:]
+	}
+	return items, nil
+}
+
+func (c *coldTail) readDisk(start, end int) ([]Score, error) {
+	if c.index == nil {
+		return nil, nil
+	}
+	return c.index.read(c.skip+start, c.skip+end)
+}
+
+// each calls fn with every tail entry in rank order.
+func (c *coldTail) each(fn func(Score) error) error {
+	pending := c.pending
+	if c.index != nil {
+		err := c.index.each(c.skip, func(sc Score) error {
+			for len(pending) > 0 && RanksAbove(pending[0], sc) {
+				if err := fn(pending[0]); err != nil {
+					return err
+				}
+				pending = pending[1:]
+			}
+			return fn(sc)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, sc := range pending {
+		if err := fn(sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// find looks id up in the tail and returns the entry and its position.
+// pending is scanned; the index is searched through its ID table.
+func (c *coldTail) find(id int) (Score, int, bool, error) {
+	for i, sc := range c.pending {
+		if sc.ID == id {
+			above, err := c.diskAbove(sc)
+			if err != nil {
+				return Score{}, 0, false, err
+			}
+			return sc, above + i, true, nil
+		}
+	}
+	if c.diskLen() == 0 {
+		return Score{}, 0, false, nil
+	}
+	// Records before skip were in memory when the index was written; any
+	// that left memory since are in pending.
+	rank, ok, err := c.index.lookup(id)
+	if err != nil || !ok || rank < c.skip {
+		return Score{}, 0, false, err
+	}
+	rec, err := c.index.read(rank, rank+1)
+	if err != nil {
+		return Score{}, 0, false, err
+	}
+	sc := rec[0]
+	above := sort.Search(len(c.pending), func(i int) bool {
+		return !RanksAbove(c.pending[i], sc)
+	})
+	return sc, rank - c.skip + above, true, nil
+}
+
+// snapshot returns a copy of the tail that stays readable after the caller
+// releases the store's lock; release must be called once the reads are done.
+// pending is never modified in place, so sharing it is safe.
+func (c *coldTail) snapshot() (tail coldTail, release func()) {
+	tail = *c
+	if tail.index == nil {
+		return tail, func() {}
+	}
+	tail.index.acquire()
+	return tail, func() { tail.index.Close() }
+}
+
+// setIndex makes index[skip:] the on-disk part of the tail.
+func (c *coldTail) setIndex(ix *scoreIndex, skip int) error {
+	c.index, c.skip = ix, skip
+	if c.diskLen() == 0 {
+		return nil
+	}
+	top, err := ix.read(skip, skip+1)
+	if err != nil {
+		return err
+	}
+	c.diskTop = top[0]
+	return nil
+}
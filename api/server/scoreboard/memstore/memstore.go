@@ -0,0 +1,160 @@
+// Package memstore provides an in-memory scoreboard.Store. Nothing touches the
+// filesystem, which makes it suitable for tests and throwaway boards.
+package memstore
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"fishtankhunt/api/server/scoreboard"
+)
+
+// Store keeps scores in a slice sorted by rank. The zero value is not usable;
+// call New.
+type Store struct {
+	mu       sync.RWMutex
+	ranked   []scoreboard.Score
+	nextID   int
+	revision uint64
+}
+
+var _ scoreboard.Store = (*Store)(nil)
+
+// New returns a store holding a copy of initial. New IDs continue after the
+// highest ID in initial.
+func New(initial ...scoreboard.Score) *Store {
+	s := &Store{nextID: 1}
+	s.ranked = append(s.ranked, initial...)
+	sort.Slice(s.ranked, func(i, j int) bool {
+		return scoreboard.RanksAbove(s.ranked[i], s.ranked[j])
+	})
+	for _, sc := range initial {
+		if sc.ID >= s.nextID {
+			s.nextID = sc.ID + 1
+		}
+	}
+	return s
+}
+
+// Add stores sub and reports its rank.
+func (s *Store) Add(sub scoreboard.Submission, at time.Time) (scoreboard.Placement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.newEntryLocked(sub, at)
+	rank := s.insertLocked(entry)
+	s.revision++
+	return scoreboard.Placement{Score: entry, Rank: rank, Total: len(s.ranked)}, nil
+}
+
+// AddBatch stores subs with consecutive IDs.
+func (s *Store) AddBatch(subs []scoreboard.Submission, at time.Time) ([]scoreboard.Score, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	added := make([]scoreboard.Score, 0, len(subs))
+	for _, sub := range subs {
+		entry := s.newEntryLocked(sub, at)
+		s.insertLocked(entry)
+		added = append(added, entry)
+	}
+	if len(added) > 0 {
+		s.revision++
+	}
+	return added, nil
+}
+
+// Remove deletes every score for which drop returns true.
+func (s *Store) Remove(drop func(scoreboard.Score) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kept := s.ranked[:0]
+	for _, sc := range s.ranked {
+		if !drop(sc) {
+			kept = append(kept, sc)
+		}
+	}
+	removed := len(s.ranked) - len(kept)
+	s.ranked = kept
+	if removed > 0 {
+		s.revision++
+	}
+	return removed, nil
+}
+
+// Get looks up id with a linear scan of the rank order.
+func (s *Store) Get(id int) (scoreboard.Placement, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i, sc := range s.ranked {
+		if sc.ID == id {
+			return scoreboard.Placement{Score: sc, Rank: i + 1, Total: len(s.ranked)}, true
+		}
+	}
+	return scoreboard.Placement{}, false
+}
+
+// Page returns a copy of one page in rank order.
+func (s *Store) Page(page, size int) scoreboard.ScorePage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	start, end, resolved, totalPages := scoreboard.PageBounds(len(s.ranked), page, size)
+	return scoreboard.ScorePage{
+		Items:      append([]scoreboard.Score(nil), s.ranked[start:end]...),
+		FirstRank:  start + 1,
+		Page:       resolved,
+		TotalPages: totalPages,
+		TotalItems: len(s.ranked),
+	}
+}
+
+// Scores returns a copy of every score in rank order.
+func (s *Store) Scores() []scoreboard.Score {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]scoreboard.Score{}, s.ranked...)
+}
+
+// Each calls fn with a copy of the scores, so fn may use the store.
+func (s *Store) Each(fn func(scoreboard.Score) error) error {
+	for _, sc := range s.Scores() {
+		if err := fn(sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Revision increases on every change.
+func (s *Store) Revision() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revision
+}
+
+// Close is a no-op; the scores are simply dropped with the store.
+func (s *Store) Close() error {
+	return nil
+}
+
+func (s *Store) newEntryLocked(sub scoreboard.Submission, at time.Time) scoreboard.Score {
+	entry := scoreboard.Score{
+		ID:          s.nextID,
+		Name:        sub.Name,
+		Score:       sub.Score,
+		TimeSeconds: sub.TimeSeconds,
+		CreatedAt:   at,
+	}
+	s.nextID++
+	return entry
+}
+
+// insertLocked places entry in rank order and returns its rank.
+func (s *Store) insertLocked(entry scoreboard.Score) int {
+	idx := sort.Search(len(s.ranked), func(i int) bool {
+		return scoreboard.RanksAbove(entry, s.ranked[i])
+	})
+	s.ranked = append(s.ranked, scoreboard.Score{})
+	copy(s.ranked[idx+1:], s.ranked[idx:])
+	s.ranked[idx] = entry
+	return idx + 1
+}
@@ -0,0 +1,15 @@
+package memstore_test
+
+import (
+	"testing"
+
+	"fishtankhunt/api/server/scoreboard"
+	"fishtankhunt/api/server/scoreboard/memstore"
+	"fishtankhunt/api/server/scoreboard/storetest"
+)
+
+func TestStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) scoreboard.Store {
+		return memstore.New()
+	})
+}
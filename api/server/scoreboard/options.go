@@ -0,0 +1,67 @@
+package scoreboard
+
+import (
+	"log"
+	"time"
+)
+
+// Option configures a Server built by New.
+type Option func(*options)
+
+type options struct {
+	cfg         Config
+	store       Store
+	boardStores map[string]Store
+	clock       Clock
+	logger      *log.Logger
+	hooks       hooks
+}
+
+// WithConfig replaces DefaultConfig. Settings that only concern the scores
+// file are ignored when WithStore is also given.
+func WithConfig(cfg Config) Option {
+	return func(o *options) { o.cfg = cfg }
+}
+
+// WithStore serves scores from store instead of the configured file, for
+// example a memstore.Store in tests. The Server takes ownership and closes it.
+func WithStore(store Store) Option {
+	return func(o *options) { o.store = store }
+}
+
+// WithBoardStore serves the board of the tenant called name from store
+// instead of its scores file. The Server takes ownership and closes it.
+func WithBoardStore(name string, store Store) Option {
+	return func(o *options) {
+		if o.boardStores == nil {
+			o.boardStores = make(map[string]Store)
+		}
+		o.boardStores[name] = store
+	}
+}
+
+// WithClock sets the clock used for submission timestamps, cache expiry and
+// tenant rate limits.
+func WithClock(clock Clock) Option {
+	return func(o *options) { o.clock = clock }
+}
+
+// WithLogger sends request and persistence logs to logger instead of the
+// standard logger.
+func WithLogger(logger *log.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// Clock reports the current time.
+type Clock interface {
+	Now() time.Time
+}
+
+// ClockFunc adapts a function to Clock.
+type ClockFunc func() time.Time
+
+func (f ClockFunc) Now() time.Time { return f() }
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
@@ -0,0 +1,119 @@
+package scoreboard
+
+import (
+	"context"
+	"net/http"
+)
+
+// Route describes one endpoint of the API. Server.Routes lists them for tools
+// such as API documentation generators, and Name labels requests in logs and
+// in RouteName.
+type Route struct {
+	Method  string
+	Path    string
+	Name    string
+	Summary string
+}
+
+// Pattern returns the route as a ServeMux pattern, e.g. "GET /scores/{id}".
+func (rt Route) Pattern() string {
+	return rt.Method + " " + rt.Path
+}
+
+// boardRoutes are served per board: requests pass tenant routing, rate limits
+// and post middleware before reaching their handler.
+var boardRoutes = []struct {
+	Route
+	serve func(*scoreHandler, http.ResponseWriter, *http.Request)
+}{
+	{Route{http.MethodGet, "/scores", "listScores", "List one page of the leaderboard"}, (*scoreHandler).handleGet},
+	{Route{http.MethodPost, "/scores", "submitScore", "Submit a score and get its rank"}, (*scoreHandler).handlePost},
+	{Route{http.MethodGet, "/scores/{id}", "getScore", "Look up one score and its current rank"}, (*scoreHandler).handleGetByID},
+	{Route{http.MethodOptions, "/scores", "preflight", "CORS preflight"}, (*scoreHandler).handlePreflight},
+	{Route{http.MethodOptions, "/scores/{id}", "preflight", "CORS preflight"}, (*scoreHandler).handlePreflight},
+}
+
+// adminRoutes require the admin token.
+var adminRoutes = []struct {
+	Route
+	serve func(*adminHandler, http.ResponseWriter, *http.Request)
+}{
+	{Route{http.MethodGet, "/admin/data-file", "getDataFile", "Show the data file a board serves"}, (*adminHandler).handleGet},
+	{Route{http.MethodPost, "/admin/data-file", "swapDataFile", "Switch a board to another data file"}, (*adminHandler).handleSwap},
+}
+
+// Routes returns every route the server handles.
+func (s *Server) Routes() []Route {
+	routes := make([]Route, 0, len(boardRoutes)+len(adminRoutes))
+	for _, rt := range boardRoutes {
+		routes = append(routes, rt.Route)
+	}
+	for _, rt := range adminRoutes {
+		routes = append(routes, rt.Route)
+	}
+	return routes
+}
+
+// boardMux routes requests for one board to h.
+func boardMux(h *scoreHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, rt := range boardRoutes {
+		serve := rt.serve
+		mux.HandleFunc(rt.Pattern(), func(w http.ResponseWriter, r *http.Request) {
+			setCORSHeaders(w, r, h.allowedOrigins, h.readOnly)
+			serve(h, w, r)
+		})
+	}
+	return mux
+}
+
+// apiMux sends board routes to boards, which picks the board of the request,
+// and admin routes to admin. Every route records its name for RouteName.
+func apiMux(boards http.Handler, admin *adminHandler) *http.ServeMux {
+	mux := http.NewServeMux()
+	for _, rt := range boardRoutes {
+		mux.Handle(rt.Pattern(), labelRoute(rt.Name, boards))
+	}
+	for _, rt := range adminRoutes {
+		serve := rt.serve
+		mux.Handle(rt.Pattern(), labelRoute(rt.Name, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if admin.authorize(w, r) {
+				serve(admin, w, r)
+			}
+		})))
+	}
+	return mux
+}
+
+type routeKey struct{}
+
+type routeLabel struct {
+	name string
+}
+
+// withRouteLabel gives each request a slot for its route name. It wraps
+// everything else so that pre middleware can read the name too.
+func withRouteLabel(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, &routeLabel{})))
+	})
+}
+
+func labelRoute(name string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if label, ok := r.Context().Value(routeKey{}).(*routeLabel); ok {
+			label.name = name
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RouteName returns the Name of the route serving ctx's request, or "" if it
+// matched none. Pre middleware only sees it after calling the next handler,
+// once the request has been routed.
+func RouteName(ctx context.Context) string {
+	if label, ok := ctx.Value(routeKey{}).(*routeLabel); ok {
+		return label.name
+	}
+	return ""
+}
@@ -0,0 +1,229 @@
+// Package scoreboard implements the Fish Tank Hunt leaderboard API: a
+// file-backed score store and the HTTP handlers that serve it.
+package scoreboard
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrReadOnly is returned by Server methods, and by file stores opened with
+// ReadOnly, that would change the stored scores.
+var ErrReadOnly = errors.New("scoreboard is read-only")
+
+// readOnlyMessage explains the 403 returned to clients of a read-only server.
+const readOnlyMessage = "scoreboard is read-only: this server serves the leaderboard but does not accept submissions"
+
+// Server is a scoreboard bound to one Store, plus one Store per tenant.
+type Server struct {
+	cfg     Config
+	store   *swapStore
+	boards  map[string]*swapStore
+	clock   Clock
+	logger  *log.Logger
+	handler http.Handler
+
+	// swapMu serializes SwapDataFile calls.
+	swapMu sync.Mutex
+}
+
+// NewServer validates cfg and loads the scores file it points at. It is
+// shorthand for New(WithConfig(cfg)).
+func NewServer(cfg Config) (*Server, error) {
+	return New(WithConfig(cfg))
+}
+
+// New builds a Server from DefaultConfig and opts. Without WithStore it loads
+// the scores file named by the config; tenant boards without WithBoardStore
+// load theirs from next to it (see TenantDataPath).
+func New(opts ...Option) (*Server, error) {
+	o := options{
+		cfg:    DefaultConfig(),
+		clock:  systemClock{},
+		logger: log.Default(),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	cfg := o.cfg
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	if cfg.ReadOnly {
+		o.logger.Printf("read-only mode: submissions are rejected and the scores file is never written")
+	}
+
+	store, err := o.boardStore(o.store, cfg.DataPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		cfg:    cfg,
+		store:  store,
+		boards: make(map[string]*swapStore, len(cfg.Tenants)),
+		clock:  o.clock,
+		logger: o.logger,
+	}
+
+	var handler http.Handler = o.newScoreHandler(store)
+	if len(cfg.Tenants) > 0 {
+		router := &tenantRouter{
+			fallback:       handler,
+			boards:         make(map[string]*tenantBoard, len(cfg.Tenants)),
+			allowedOrigins: cfg.CORSOrigins,
+			readOnly:       cfg.ReadOnly,
+		}
+		for _, t := range cfg.Tenants {
+			board, err := o.boardStore(o.boardStores[t.Name], TenantDataPath(cfg.DataPath, t.Name))
+			if err != nil {
+				s.Close()
+				return nil, fmt.Errorf("tenant %s: %w", t.Name, err)
+			}
+			s.boards[t.Name] = board
+			router.boards[t.Key] = &tenantBoard{
+				name:    t.Name,
+				handler: o.newScoreHandler(board),
+				limiter: newTokenBucket(t.RateLimit, t.Burst, o.clock),
+			}
+		}
+		o.logger.Printf("serving %d tenant boards; requests without %s use the default board", len(cfg.Tenants), apiKeyHeader)
+		handler = router
+	}
+
+	mux := apiMux(handler, &adminHandler{
+		server:  s,
+		token:   cfg.AdminToken,
+		open:    cfg.Env == EnvDev,
+		dataDir: filepath.Dir(cfg.DataPath),
+		logger:  o.logger,
+	})
+	switch {
+	case cfg.AdminToken == "" && cfg.Env == EnvDev:
+		o.logger.Printf("admin API open without a token (env %s)", cfg.Env)
+	case cfg.AdminToken == "":
+		o.logger.Printf("admin API disabled: no admin token configured")
+	}
+
+	if cfg.SeedScores > 0 {
+		if err := s.seed(cfg.SeedScores); err != nil {
+			s.Close()
+			return nil, err
+		}
+	}
+	s.handler = withRouteLabel(chain(loggingMiddleware(o.logger, cfg.Verbose, mux), o.hooks.pre))
+	return s, nil
+}
+
+// boardStore wraps injected, or the file store at path when injected is nil,
+// so that its data file can be swapped later.
+func (o *options) boardStore(injected Store, path string) (*swapStore, error) {
+	if injected != nil {
+		return newSwapStore(injected, ""), nil
+	}
+	if o.cfg.Storage == StorageMemory {
+		o.logger.Printf("initializing in-memory score store; scores are lost on exit")
+		store, err := newScoreStore("", o.cfg.storeOptions(o.logger))
+		if err != nil {
+			return nil, err
+		}
+		return newSwapStore(store, ""), nil
+	}
+	absPath, _ := filepath.Abs(path)
+	o.logger.Printf("initializing score store with file path: %s (absolute: %s)", path, absPath)
+	store, err := newScoreStore(path, o.cfg.storeOptions(o.logger))
+	if err != nil {
+		return nil, err
+	}
+	return newSwapStore(store, path), nil
+}
+
+// newScoreHandler serves the board routes for store, wrapped in the post
+// middleware.
+func (o *options) newScoreHandler(store Store) http.Handler {
+	handler := &scoreHandler{
+		store:           store,
+		clock:           o.clock,
+		logger:          o.logger,
+		allowedOrigins:  o.cfg.CORSOrigins,
+		defaultPageSize: o.cfg.DefaultPageSize,
+		maxPageSize:     o.cfg.MaxPageSize,
+		readOnly:        o.cfg.ReadOnly,
+		hooks:           o.hooks,
+	}
+	if o.cfg.ScoresCacheTTL > 0 {
+		handler.cache = newResponseCache(time.Duration(o.cfg.ScoresCacheTTL), o.clock)
+	}
+	return chain(boardMux(handler), o.hooks.post)
+}
+
+// Handler returns the HTTP handler serving the scoreboard API.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Config returns the configuration the server was created with.
+func (s *Server) Config() Config {
+	return s.cfg
+}
+
+// Scores returns a copy of every stored score in rank order.
+func (s *Server) Scores() []Score {
+	return s.store.Scores()
+}
+
+// EachScore calls fn with every score of the default board in rank order,
+// without copying the board first; see Store.Each.
+func (s *Server) EachScore(fn func(Score) error) error {
+	return s.store.Each(fn)
+}
+
+// AddScores stores subs as one batch. It is meant for offline tools such as
+// seeding; the API adds scores one at a time.
+func (s *Server) AddScores(subs []Submission) ([]Score, error) {
+	if s.cfg.ReadOnly {
+		return nil, ErrReadOnly
+	}
+	clean := make([]Submission, len(subs))
+	for i, sub := range subs {
+		sub.Name = sanitizeName(sub.Name)
+		clean[i] = sub
+	}
+	return s.store.AddBatch(clean, s.clock.Now().UTC())
+}
+
+// RemoveScores deletes every score for which drop returns true and reports
+// how many were removed.
+func (s *Server) RemoveScores(drop func(Score) bool) (int, error) {
+	if s.cfg.ReadOnly {
+		return 0, ErrReadOnly
+	}
+	return s.store.Remove(drop)
+}
+
+// Rewrite writes the scores file in the configured format, converting a file
+// that was loaded in another format. Stores without a file cannot be
+// rewritten.
+func (s *Server) Rewrite() error {
+	if s.cfg.ReadOnly {
+		return ErrReadOnly
+	}
+	return s.store.Rewrite()
+}
+
+// Close closes every board's store, including ones passed with WithStore or
+// WithBoardStore. File stores stop background persistence and flush pending
+// scores to disk.
+func (s *Server) Close() error {
+	errs := []error{s.store.Close()}
+	for name, board := range s.boards {
+		if err := board.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,343 @@
+package scoreboard_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"fishtankhunt/api/server/scoreboard"
+	"fishtankhunt/api/server/scoreboard/memstore"
+)
+
+// fakeClock is a Clock the test moves by hand.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+type placedScore struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Rank int    `json:"rank"`
+}
+
+func TestHandlerWithMemstore(t *testing.T) {
+	noon := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeClock{now: noon}
+	store := memstore.New()
+	sb, err := scoreboard.New(
+		scoreboard.WithStore(store),
+		scoreboard.WithClock(clock),
+		scoreboard.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { sb.Close() })
+	srv := httptest.NewServer(sb.Handler())
+	t.Cleanup(srv.Close)
+
+	post := func(name string, score int) placedScore {
+		t.Helper()
+		body := fmt.Sprintf(`{"name":%q,"score":%d,"timeSeconds":30}`, name, score)
+		resp, err := http.Post(srv.URL+"/scores", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST /scores: %v", err)
+		}
+		var placed placedScore
+		decode(t, resp, http.StatusCreated, &placed)
+		return placed
+	}
+
+	late := post("late", 500)
+	if late.ID != 1 || late.Rank != 1 {
+		t.Fatalf("first submission = id %d rank %d, want id 1 rank 1", late.ID, late.Rank)
+	}
+	// An equal score submitted earlier ranks higher.
+	clock.Set(noon.Add(-time.Hour))
+	early := post("early", 500)
+	if early.ID != 2 || early.Rank != 1 {
+		t.Fatalf("earlier submission = id %d rank %d, want id 2 rank 1", early.ID, early.Rank)
+	}
+
+	resp, err := http.Get(srv.URL + "/scores?page=1&size=5")
+	if err != nil {
+		t.Fatalf("GET /scores: %v", err)
+	}
+	var page struct {
+		Items      []placedScore `json:"items"`
+		TotalItems int           `json:"totalItems"`
+	}
+	decode(t, resp, http.StatusOK, &page)
+	if page.TotalItems != 2 || len(page.Items) != 2 || page.Items[0].Name != "early" || page.Items[1].Name != "late" {
+		t.Errorf("GET /scores = %+v, want early then late", page)
+	}
+
+	// Pages above maxPageSize are refused, including sizes that would
+	// overflow the page arithmetic.
+	for _, size := range []string{"1001", "9223372036854775807"} {
+		resp, err := http.Get(srv.URL + "/scores?size=" + size)
+		if err != nil {
+			t.Fatalf("GET /scores: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("GET /scores?size=%s = %d, want 400", size, resp.StatusCode)
+		}
+	}
+
+	resp, err = http.Get(srv.URL + "/scores/1")
+	if err != nil {
+		t.Fatalf("GET /scores/1: %v", err)
+	}
+	var got placedScore
+	decode(t, resp, http.StatusOK, &got)
+	if got.Name != "late" || got.Rank != 2 {
+		t.Errorf("GET /scores/1 = %+v, want late at rank 2", got)
+	}
+
+	if created := store.Scores()[0].CreatedAt; !created.Equal(noon.Add(-time.Hour)) {
+		t.Errorf("stored CreatedAt = %v, want the fake clock's %v", created, noon.Add(-time.Hour))
+	}
+}
+
+func decode(t *testing.T, resp *http.Response, status int, v any) {
+	t.Helper()
+	defer resp.Body.Close()
+	if resp.StatusCode != status {
+		body, _ := io.ReadAll(resp.Body)
+		t.Fatalf("%s %s = %d %q, want %d", resp.Request.Method, resp.Request.URL.Path, resp.StatusCode, body, status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("decode %s: %v", resp.Request.URL.Path, err)
+	}
+}
+
+// request sends a request with an optional API key and JSON body.
+func request(t *testing.T, method, url, apiKey, body string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, url, err)
+	}
+	return resp
+}
+
+func TestTenants(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)}
+	cfg := scoreboard.DefaultConfig()
+	cfg.Tenants = []scoreboard.Tenant{
+		{Name: "pond", Key: "pond-key", RateLimit: 1, Burst: 2},
+		{Name: "reef", Key: "reef-key"},
+	}
+	sb, err := scoreboard.New(
+		scoreboard.WithConfig(cfg),
+		scoreboard.WithStore(memstore.New()),
+		scoreboard.WithBoardStore("pond", memstore.New()),
+		scoreboard.WithBoardStore("reef", memstore.New()),
+		scoreboard.WithClock(clock),
+		scoreboard.WithLogger(log.New(io.Discard, "", 0)),
+	)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { sb.Close() })
+	srv := httptest.NewServer(sb.Handler())
+	t.Cleanup(srv.Close)
+
+	var placed placedScore
+	decode(t, request(t, http.MethodPost, srv.URL+"/scores", "pond-key", `{"name":"pond-player","score":10}`), http.StatusCreated, &placed)
+
+	// Each key sees only its own board, and requests without a key use the
+	// default board.
+	for _, tc := range []struct {
+		key   string
+		total int
+	}{{"reef-key", 0}, {"", 0}, {"pond-key", 1}} {
+		var page struct {
+			TotalItems int `json:"totalItems"`
+		}
+		decode(t, request(t, http.MethodGet, srv.URL+"/scores", tc.key, ""), http.StatusOK, &page)
+		if page.TotalItems != tc.total {
+			t.Errorf("GET /scores with key %q has %d scores, want %d", tc.key, page.TotalItems, tc.total)
+		}
+	}
+	resp := request(t, http.MethodGet, fmt.Sprintf("%s/scores/%d", srv.URL, placed.ID), "reef-key", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("reef reading pond's score = %d, want 404", resp.StatusCode)
+	}
+
+	resp = request(t, http.MethodGet, srv.URL+"/scores", "no-such-key", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /scores with an unknown key = %d, want 401", resp.StatusCode)
+	}
+
+	// pond's burst of 2 is used up by the POST and GET above.
+	resp = request(t, http.MethodGet, srv.URL+"/scores", "pond-key", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "1" {
+		t.Errorf("third pond request = %d with Retry-After %q, want 429 with 1", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	// reef has no limit, and pond's limit does not touch it.
+	for i := 0; i < 5; i++ {
+		resp = request(t, http.MethodGet, srv.URL+"/scores", "reef-key", "")
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("reef request %d = %d, want 200", i, resp.StatusCode)
+		}
+	}
+	clock.Set(clock.Now().Add(time.Second))
+	resp = request(t, http.MethodGet, srv.URL+"/scores", "pond-key", "")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("pond request a second later = %d, want 200", resp.StatusCode)
+	}
+}
+
+const adminToken = "0123456789abcdef"
+
+// swap posts body to the admin swap route and returns the status.
+func swap(t *testing.T, url, token, body string) int {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url+"/admin/data-file", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST /admin/data-file: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// newFileServer serves cfg's boards from scores files in a temp directory.
+func newFileServer(t *testing.T, cfg scoreboard.Config) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	cfg.DataPath = filepath.Join(dir, "scores.json")
+	sb, err := scoreboard.New(scoreboard.WithConfig(cfg), scoreboard.WithLogger(log.New(io.Discard, "", 0)))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { sb.Close() })
+	srv := httptest.NewServer(sb.Handler())
+	t.Cleanup(srv.Close)
+	return srv.URL, dir
+}
+
+func TestSwapDataFile(t *testing.T) {
+	cfg := scoreboard.DefaultConfig()
+	cfg.AdminToken = adminToken
+	// Submissions stay pending until the swap flushes them.
+	cfg.PersistInterval = scoreboard.Duration(time.Hour)
+	cfg.Tenants = []scoreboard.Tenant{{Name: "pond", Key: "pond-key"}}
+	url, dir := newFileServer(t, cfg)
+
+	resp := request(t, http.MethodPost, url+"/scores", "", `{"name":"round1","score":10}`)
+	resp.Body.Close()
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{not json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		name, token, body string
+		want              int
+	}{
+		{"no token", "", `{"file":"round2.json","create":true}`, http.StatusUnauthorized},
+		{"wrong token", "wrong-token-0000", `{"file":"round2.json","create":true}`, http.StatusUnauthorized},
+		{"outside data dir", adminToken, `{"file":"../round2.json","create":true}`, http.StatusBadRequest},
+		{"own file", adminToken, `{"file":"scores.json"}`, http.StatusConflict},
+		{"tenant's file", adminToken, `{"file":"tenants/pond/scores.json"}`, http.StatusConflict},
+		{"missing file", adminToken, `{"file":"missing.json"}`, http.StatusUnprocessableEntity},
+		{"undecodable file", adminToken, `{"file":"broken.json"}`, http.StatusUnprocessableEntity},
+		{"unknown board", adminToken, `{"board":"reef","file":"round2.json","create":true}`, http.StatusNotFound},
+	} {
+		if got := swap(t, url, tc.token, tc.body); got != tc.want {
+			t.Errorf("swap with %s = %d, want %d", tc.name, got, tc.want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "scores.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("refused swaps flushed the current file: %v", err)
+	}
+
+	if got := swap(t, url, adminToken, `{"file":"round2.json","create":true}`); got != http.StatusOK {
+		t.Fatalf("swap to round2.json = %d, want 200", got)
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, "scores.json"))
+	if err != nil {
+		t.Fatalf("previous file was not flushed: %v", err)
+	}
+	var round1 []scoreboard.Score
+	if err := json.Unmarshal(raw, &round1); err != nil || len(round1) != 1 || round1[0].Name != "round1" {
+		t.Errorf("previous file holds %s, want the round1 score", raw)
+	}
+
+	var current struct {
+		DataPath string `json:"dataPath"`
+		Scores   int    `json:"scores"`
+	}
+	req, _ := http.NewRequest(http.MethodGet, url+"/admin/data-file", nil)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /admin/data-file: %v", err)
+	}
+	decode(t, resp, http.StatusOK, &current)
+	if filepath.Base(current.DataPath) != "round2.json" || current.Scores != 0 {
+		t.Errorf("GET /admin/data-file = %+v, want round2.json with 0 scores", current)
+	}
+}
+
+func TestSwapDataFileRefused(t *testing.T) {
+	disabled := scoreboard.DefaultConfig()
+	url, _ := newFileServer(t, disabled)
+	if got := swap(t, url, adminToken, `{"file":"round2.json","create":true}`); got != http.StatusForbidden {
+		t.Errorf("swap without a configured admin token = %d, want 403", got)
+	}
+
+	readOnly := scoreboard.DefaultConfig()
+	readOnly.AdminToken = adminToken
+	readOnly.ReadOnly = true
+	url, dir := newFileServer(t, readOnly)
+	if got := swap(t, url, adminToken, `{"file":"round2.json","create":true}`); got != http.StatusForbidden {
+		t.Errorf("swap on a read-only server = %d, want 403", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "round2.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("read-only server created round2.json: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package scoreboard
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// RandomSubmissions returns n demo submissions with three-letter names drawn
+// from rng.
+func RandomSubmissions(rng *rand.Rand, n int) []Submission {
+	const letters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	subs := make([]Submission, n)
+	for i := range subs {
+		name := []byte{letters[rng.Intn(26)], letters[rng.Intn(26)], letters[rng.Intn(26)]}
+		subs[i] = Submission{
+			Name:        string(name),
+			Score:       rng.Intn(300000),
+			TimeSeconds: 5 + rng.Intn(115),
+		}
+	}
+	return subs
+}
+
+// seed adds n demo scores to the default board if it is empty, so a fresh
+// development server has something to show.
+func (s *Server) seed(n int) error {
+	if s.store.Page(1, 1).TotalItems > 0 {
+		return nil
+	}
+	now := s.clock.Now()
+	rng := rand.New(rand.NewSource(now.UnixNano()))
+	if _, err := s.store.AddBatch(RandomSubmissions(rng, n), now.UTC()); err != nil {
+		return fmt.Errorf("seed scores: %w", err)
+	}
+	s.logger.Printf("seeded the default board with %d demo scores", n)
+	return nil
+}
@@ -0,0 +1,1121 @@
+package scoreboard
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Score represents a single leaderboard submission.
+type Score struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Score       int       `json:"score"`
+	TimeSeconds int       `json:"timeSeconds"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// Submission is a score to be added to the board.
+type Submission struct {
+	Name        string
+	Score       int
+	TimeSeconds int
+}
+
+// Store holds the scores behind a Server. Implementations must be safe for
+// concurrent use and rank entries with RanksAbove.
+type Store interface {
+	// Add stores sub under the next free ID with CreatedAt set to at and
+	// reports where it landed.
+	Add(sub Submission, at time.Time) (Placement, error)
+	// AddBatch stores subs with consecutive IDs, all created at at.
+	AddBatch(subs []Submission, at time.Time) ([]Score, error)
+	// Remove deletes every score for which drop returns true and reports how
+	// many were removed.
+	Remove(drop func(Score) bool) (int, error)
+	// Get looks up the score with id and reports its current rank.
+	Get(id int) (Placement, bool)
+	// Page returns one page of scores in rank order, resolved with
+	// PageBounds.
+	Page(page, size int) ScorePage
+	// Scores returns a copy of every score in rank order.
+	Scores() []Score
+	// Each calls fn with every score in rank order and stops at the first
+	// error fn returns, which Each returns. Unlike Scores, it does not need
+	// the whole board in memory at once.
+	Each(fn func(Score) error) error
+	// Revision increases whenever the stored scores change.
+	Revision() uint64
+	// Close releases the store, persisting anything still pending.
+	Close() error
+}
+
+// Placement reports where a score ranks among Total entries.
+type Placement struct {
+	Score Score
+	Rank  int
+	Total int
+}
+
+// ScorePage is one page of the leaderboard. Items[i] has rank FirstRank+i.
+type ScorePage struct {
+	Items      []Score
+	FirstRank  int
+	Page       int
+	TotalPages int
+	TotalItems int
+}
+
+// RanksAbove reports whether a ranks above b: higher scores first, earlier
+// submissions first among equal scores, and lower IDs first after that.
+func RanksAbove(a, b Score) bool {
+	switch {
+	case a.Score != b.Score:
+		return a.Score > b.Score
+	case !a.CreatedAt.Equal(b.CreatedAt):
+		return a.CreatedAt.Before(b.CreatedAt)
+	}
+	return a.ID < b.ID
+}
+
+// PageBounds resolves a requested page against totalItems entries. Pages
+// below 1 resolve to the first page and pages past the end to the last one;
+// a non-positive size falls back to 5, and a size above totalItems is treated
+// as totalItems so the arithmetic cannot overflow. Items [start, end) belong
+// to the page.
+func PageBounds(totalItems, page, size int) (start, end, resolvedPage, totalPages int) {
+	if size <= 0 {
+		size = 5
+	}
+	if size > totalItems {
+		size = max(totalItems, 1)
+	}
+	if page <= 0 {
+		page = 1
+	}
+
+	totalPages = 1
+	if totalItems > 0 {
+		totalPages = (totalItems + size - 1) / size
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	start = (page - 1) * size
+	if start > totalItems {
+		start = totalItems
+	}
+
+	end = start + size
+	if end > totalItems {
+		end = totalItems
+	}
+	return start, end, page, totalPages
+}
+
+// scoreStore is the file-backed Store used unless New is given another.
+type scoreStore struct {
+	mu       sync.RWMutex
+	scores   []Score
+	nextID   int
+	filePath string
+	format   string
+
+	// persistMu serializes file writes so a slow disk never holds mu. Lock
+	// order is persistMu before mu. persistedRev is guarded by persistMu.
+	persistMu    sync.Mutex
+	persistedRev uint64
+
+	// persistInterval > 0 coalesces writes: add leaves the new revision
+	// unpersisted and the flusher goroutine writes at most once per interval.
+	persistInterval time.Duration
+	stopFlusher     chan struct{}
+	flusherDone     chan struct{}
+
+	// revision increases on every change to scores. order holds positions in
+	// scores sorted by rank and is kept in step with it; like scores, it is
+	// replaced rather than modified so readers can keep using snapshots.
+	revision uint64
+	order    []int32
+
+	// maxScores > 0 caps how many scores are held in memory. Once the board
+	// outgrows it, the lower-ranked scores move to cold and are read from a
+	// rank index when needed; nothing is deleted.
+	maxScores int
+	cold      *coldTail
+	// indexFile is where the rank index is written: next to the scores file,
+	// except for read-only stores, which use a temp file (tempIndex) removed
+	// on Close. Guarded by persistMu once the store is open.
+	indexFile string
+	tempIndex bool
+
+	// readOnly stores never write the scores file, not even on Close; Add,
+	// AddBatch, Remove and Rewrite fail with ErrReadOnly.
+	readOnly bool
+
+	logger *log.Logger
+}
+
+type storeOptions struct {
+	persistInterval time.Duration
+	format          string
+	maxScores       int
+	readOnly        bool
+	logger          *log.Logger
+}
+
+// OpenFileStore loads the scores file at path into a Store that writes it
+// back according to cfg's Format, PersistInterval, MaxScores and ReadOnly
+// settings. New opens one automatically unless WithStore is given.
+func OpenFileStore(path string, cfg Config) (Store, error) {
+	store, err := newScoreStore(path, cfg.storeOptions(log.Default()))
+	if err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func newScoreStore(filePath string, opts storeOptions) (*scoreStore, error) {
+	if opts.format == "" {
+		opts.format = FormatJSON
+	}
+	if !validFormat(opts.format) {
+		return nil, fmt.Errorf("unknown scores format %q", opts.format)
+	}
+	if opts.logger == nil {
+		opts.logger = log.Default()
+	}
+	if opts.maxScores > 0 && filePath == "" {
+		return nil, errors.New("maxScores needs a scores file to keep the scores beyond the cap")
+	}
+	store := &scoreStore{
+		nextID:          1,
+		filePath:        filePath,
+		format:          opts.format,
+		persistInterval: opts.persistInterval,
+		maxScores:       opts.maxScores,
+		readOnly:        opts.readOnly,
+		logger:          opts.logger,
+	}
+	if store.maxScores > 0 {
+		if store.readOnly {
+			store.tempIndex = true
+		} else {
+			store.indexFile = indexPath(filePath)
+		}
+	}
+	if err := store.loadFromFile(); err != nil {
+		return nil, err
+	}
+	if err := store.restoreNextID(); err != nil {
+		return nil, err
+	}
+	if store.persistInterval > 0 && !store.readOnly {
+		store.stopFlusher = make(chan struct{})
+		store.flusherDone = make(chan struct{})
+		go store.runFlusher()
+	}
+	return store, nil
+}
+
+func (s *scoreStore) runFlusher() {
+	defer close(s.flusherDone)
+	ticker := time.NewTicker(s.persistInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.flush(); err != nil {
+				s.logger.Printf("deferred persist failed, will retry: %v", err)
+			}
+		case <-s.stopFlusher:
+			return
+		}
+	}
+}
+
+// flush persists pending writes, if any.
+func (s *scoreStore) flush() error {
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+	return s.persistSnapshotLocked()
+}
+
+// persistSnapshotLocked writes the current scores if they are newer than the
+// file. Only the snapshot is taken under mu; encoding and disk I/O run without
+// it so readers are never blocked by a flush. Callers must hold persistMu.
+func (s *scoreStore) persistSnapshotLocked() error {
+	s.mu.RLock()
+	// Entries are never modified in place and removals build a new slice, so
+	// capping the slice makes it an immutable snapshot.
+	snapshot := s.scores[:len(s.scores):len(s.scores)]
+	rev := s.revision
+	var hot []Score
+	var tail coldTail
+	spilled := s.cold != nil
+	if spilled {
+		hot = s.rankedLocked()
+		tail = *s.cold
+	}
+	s.mu.RUnlock()
+
+	if rev <= s.persistedRev {
+		return nil
+	}
+	if spilled {
+		_, err := s.persistSpilledLocked(hot, tail, rev, nil)
+		return err
+	}
+	if err := s.writeScores(snapshot); err != nil {
+		return err
+	}
+	s.persistedRev = rev
+	return nil
+}
+
+// persistSpilledLocked writes a board that has a cold tail: hot (the scores
+// in memory, in rank order) followed by the tail, minus any entries of the
+// tail's index for which drop returns true. The scores file and a new rank
+// index are written in one pass, and the new index replaces the old one.
+// rev is the revision hot and tail were taken at. Callers must hold
+// persistMu.
+func (s *scoreStore) persistSpilledLocked(hot []Score, tail coldTail, rev uint64, drop func(Score) bool) (int, error) {
+	ix, removed, err := s.writeSpilled(hot, &tail, drop, !s.readOnly)
+	if err != nil {
+		return 0, err
+	}
+
+	written := make(map[int]bool, len(tail.pending))
+	for _, sc := range tail.pending {
+		written[sc.ID] = true
+	}
+	s.mu.Lock()
+	old := s.cold.index
+	var pending []Score
+	for _, sc := range s.cold.pending {
+		if !written[sc.ID] {
+			pending = append(pending, sc)
+		}
+	}
+	s.cold.pending = pending
+	err = s.cold.setIndex(ix, len(hot))
+	if removed > 0 {
+		// Readers saw the dropped entries until now, so the change only
+		// becomes visible with this revision.
+		if s.revision == rev {
+			rev++
+		}
+		s.revision++
+	}
+	s.mu.Unlock()
+	if old != nil {
+		old.Close()
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.persistedRev = rev
+	return removed, nil
+}
+
+// writeSpilled writes hot followed by tail to a new rank index and, with
+// writeData, to the scores file, and returns the opened index.
+func (s *scoreStore) writeSpilled(hot []Score, tail *coldTail, drop func(Score) bool, writeData bool) (*scoreIndex, int, error) {
+	dir := filepath.Dir(s.filePath)
+	indexDir := dir
+	if s.tempIndex {
+		indexDir = ""
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, 0, err
+	}
+	iw, err := createIndex(indexDir)
+	if err != nil {
+		return nil, 0, err
+	}
+	var tmp *os.File
+	var sw *scoresWriter
+	fail := func(err error) (*scoreIndex, int, error) {
+		iw.abort()
+		if sw != nil {
+			sw.abort()
+		}
+		if tmp != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+		s.logger.Printf("failed to write scores with rank index: %v", err)
+		return nil, 0, err
+	}
+	if writeData {
+		if tmp, err = os.CreateTemp(dir, "scores-*.tmp"); err != nil {
+			return fail(err)
+		}
+		if sw, err = newScoresWriter(tmp, s.format); err != nil {
+			return fail(err)
+		}
+	}
+
+	total, removed := 0, 0
+	emit := func(sc Score) error {
+		total++
+		if sw != nil {
+			if err := sw.add(sc); err != nil {
+				return err
+			}
+		}
+		return iw.add(sc)
+	}
+	for _, sc := range hot {
+		if err := emit(sc); err != nil {
+			return fail(err)
+		}
+	}
+	err = tail.each(func(sc Score) error {
+		if drop != nil && drop(sc) {
+			removed++
+			return nil
+		}
+		return emit(sc)
+	})
+	if err != nil {
+		return fail(err)
+	}
+
+	if tmp != nil {
+		s.logger.Printf("persisting %d scores to %s with rank index %s", total, s.filePath, indexPath(s.filePath))
+		if err := sw.close(); err != nil {
+			return fail(err)
+		}
+		if err := tmp.Sync(); err != nil {
+			return fail(err)
+		}
+		if err := tmp.Close(); err != nil {
+			os.Remove(tmp.Name())
+			tmp = nil
+			return fail(err)
+		}
+		if err := os.Rename(tmp.Name(), s.filePath); err != nil {
+			os.Remove(tmp.Name())
+			tmp = nil
+			return fail(err)
+		}
+		tmp = nil
+	}
+	data, err := os.Stat(s.filePath)
+	if err != nil {
+		return fail(err)
+	}
+	target := s.indexFile
+	if target == "" {
+		target = iw.f.Name()
+	}
+	if err := iw.finish(target, data); err != nil {
+		s.logger.Printf("failed to write rank index %s: %v", target, err)
+		return nil, 0, err
+	}
+	s.indexFile = target
+	ix, err := openScoreIndex(target, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	return ix, removed, nil
+}
+
+// Rewrite writes the current scores in the configured format even when the
+// file is already up to date.
+func (s *scoreStore) Rewrite() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+	s.persistedRev = 0
+	return s.persistSnapshotLocked()
+}
+
+// Close stops the flusher and writes any pending scores to disk.
+func (s *scoreStore) Close() error {
+	if s.stopFlusher != nil {
+		close(s.stopFlusher)
+		<-s.flusherDone
+		s.stopFlusher = nil
+	}
+	var err error
+	if !s.readOnly {
+		err = s.flush()
+	}
+	s.mu.Lock()
+	if s.cold != nil && s.cold.index != nil {
+		s.cold.index.Close()
+	}
+	s.mu.Unlock()
+	if s.tempIndex && s.indexFile != "" {
+		os.Remove(s.indexFile)
+	}
+	return err
+}
+
+// Add stores sub and, unless writes are coalesced, persists it before
+// returning; a failed write rolls the entry back.
+func (s *scoreStore) Add(sub Submission, at time.Time) (Placement, error) {
+	if s.readOnly {
+		return Placement{}, ErrReadOnly
+	}
+	s.mu.Lock()
+	entry := Score{
+		ID:          s.nextID,
+		Name:        sub.Name,
+		Score:       sub.Score,
+		TimeSeconds: sub.TimeSeconds,
+		CreatedAt:   at,
+	}
+	var placed Placement
+	if s.cold != nil && s.cold.len() > 0 && !s.cold.ranksAboveTail(entry) {
+		pos, err := s.cold.insert(entry)
+		if err != nil {
+			s.mu.Unlock()
+			return Placement{}, err
+		}
+		placed = Placement{Score: entry, Rank: len(s.scores) + pos + 1}
+	} else {
+		s.scores = append(s.scores, entry)
+		placed = Placement{Score: entry, Rank: s.insertOrderLocked(len(s.scores) - 1)}
+		s.spillLocked()
+	}
+	s.nextID++
+	s.revision++
+	placed.Total = s.totalLocked()
+	s.mu.Unlock()
+
+	if s.persistInterval > 0 {
+		return placed, nil
+	}
+
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+	if err := s.persistSnapshotLocked(); err != nil {
+		s.rollback(entry.ID)
+		return Placement{}, err
+	}
+	return placed, nil
+}
+
+// AddBatch stores subs with consecutive IDs and rebuilds the rank order once.
+// Like Add, it persists straight away unless writes are coalesced.
+func (s *scoreStore) AddBatch(subs []Submission, at time.Time) ([]Score, error) {
+	if s.readOnly {
+		return nil, ErrReadOnly
+	}
+	s.mu.Lock()
+	added := make([]Score, 0, len(subs))
+	scores := make([]Score, len(s.scores), len(s.scores)+len(subs))
+	copy(scores, s.scores)
+	var toCold []Score
+	for _, sub := range subs {
+		entry := Score{
+			ID:          s.nextID,
+			Name:        sub.Name,
+			Score:       sub.Score,
+			TimeSeconds: sub.TimeSeconds,
+			CreatedAt:   at,
+		}
+		s.nextID++
+		added = append(added, entry)
+		if s.cold != nil && s.cold.len() > 0 && !s.cold.ranksAboveTail(entry) {
+			toCold = append(toCold, entry)
+			continue
+		}
+		scores = append(scores, entry)
+	}
+	s.scores = scores
+	if len(toCold) > 0 {
+		s.cold.pending = mergeRanked(s.cold.pending, toCold)
+	}
+	s.markChangedLocked()
+	s.spillLocked()
+	s.mu.Unlock()
+
+	return added, s.persistIfSync()
+}
+
+// Remove drops every score for which drop returns true and reports how many
+// were removed. Like Add, it persists straight away unless writes are
+// coalesced. A board with a cold tail is always rewritten straight away,
+// since that is when the tail is filtered.
+func (s *scoreStore) Remove(drop func(Score) bool) (int, error) {
+	if s.readOnly {
+		return 0, ErrReadOnly
+	}
+	s.persistMu.Lock()
+	defer s.persistMu.Unlock()
+
+	s.mu.Lock()
+	kept := make([]Score, 0, len(s.scores))
+	for _, sc := range s.scores {
+		if !drop(sc) {
+			kept = append(kept, sc)
+		}
+	}
+	removed := len(s.scores) - len(kept)
+	if removed > 0 {
+		s.scores = kept
+		s.markChangedLocked()
+	}
+	nextID := s.nextID
+	if s.cold == nil {
+		s.mu.Unlock()
+		if removed == 0 {
+			return 0, nil
+		}
+		if err := s.saveNextID(nextID); err != nil {
+			return removed, err
+		}
+		if s.persistInterval > 0 {
+			return removed, nil
+		}
+		return removed, s.persistSnapshotLocked()
+	}
+
+	var pending []Score
+	for _, sc := range s.cold.pending {
+		if !drop(sc) {
+			pending = append(pending, sc)
+		}
+	}
+	if n := len(s.cold.pending) - len(pending); n > 0 {
+		removed += n
+		s.cold.pending = pending
+		s.revision++
+	}
+	hot, tail, rev := s.rankedLocked(), *s.cold, s.revision
+	s.mu.Unlock()
+
+	// Whether the index loses entries is only known once it is rewritten,
+	// so the ID mark is saved first either way.
+	if err := s.saveNextID(nextID); err != nil {
+		return removed, err
+	}
+	fromIndex, err := s.persistSpilledLocked(hot, tail, rev, drop)
+	return removed + fromIndex, err
+}
+
+// persistIfSync flushes unless the flusher goroutine owns persistence.
+func (s *scoreStore) persistIfSync() error {
+	if s.persistInterval > 0 {
+		return nil
+	}
+	return s.flush()
+}
+
+// rollback drops the entry with id after a failed persist. Callers must hold
+// persistMu.
+func (s *scoreStore) rollback(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	found := false
+	for i, sc := range s.scores {
+		if sc.ID == id {
+			s.removeAtLocked(i)
+			found = true
+			break
+		}
+	}
+	if !found && s.cold != nil {
+		var pending []Score
+		for _, sc := range s.cold.pending {
+			if sc.ID != id {
+				pending = append(pending, sc)
+			}
+		}
+		s.cold.pending = pending
+		s.revision++
+	}
+	if s.nextID == id+1 {
+		s.nextID = id
+	}
+}
+
+// insertOrderLocked places scores[pos] into the rank order and returns its
+// rank. The order is copied, so this costs O(n) but never re-sorts.
+func (s *scoreStore) insertOrderLocked(pos int) int {
+	entry := s.scores[pos]
+	idx := sort.Search(len(s.order), func(i int) bool {
+		return RanksAbove(entry, s.scores[s.order[i]])
+	})
+	order := make([]int32, len(s.order)+1)
+	copy(order, s.order[:idx])
+	order[idx] = int32(pos)
+	copy(order[idx+1:], s.order[idx:])
+	s.order = order
+	return idx + 1
+}
+
+// removeAtLocked drops scores[pos], copying both slices so snapshots taken by
+// readers and in-flight persists stay intact.
+func (s *scoreStore) removeAtLocked(pos int) {
+	scores := make([]Score, 0, len(s.scores)-1)
+	scores = append(scores, s.scores[:pos]...)
+	scores = append(scores, s.scores[pos+1:]...)
+
+	order := make([]int32, 0, len(s.order)-1)
+	for _, p := range s.order {
+		switch {
+		case int(p) == pos:
+			continue
+		case int(p) > pos:
+			p--
+		}
+		order = append(order, p)
+	}
+
+	s.scores = scores
+	s.order = order
+	s.revision++
+}
+
+func (s *scoreStore) loadFromFile() error {
+	if s.filePath == "" {
+		return nil
+	}
+	if s.maxScores > 0 && s.loadFromIndex() {
+		return nil
+	}
+	f, err := os.Open(s.filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			s.logger.Printf("scores file not found at %s, starting with empty scores", s.filePath)
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	stored, format, err := decodeScores(f)
+	if errors.Is(err, errEmptyScoresFile) {
+		s.logger.Printf("scores file at %s is empty, starting with empty scores", s.filePath)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("decode %s scores file: %w", format, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scores = stored
+	maxID := 0
+	for _, sc := range stored {
+		if sc.ID > maxID {
+			maxID = sc.ID
+		}
+	}
+	s.nextID = maxID + 1
+	if s.nextID <= 1 {
+		s.nextID = 1
+	}
+	s.markChangedLocked()
+	s.logger.Printf("loaded %d scores from %s (format: %s, next ID: %d)", len(s.scores), s.filePath, format, s.nextID)
+	if s.maxScores > 0 && len(s.scores) > s.maxScores {
+		// Build the index now so the memory goes back and the next start
+		// only reads the top entries.
+		s.spillLocked()
+		ix, _, err := s.writeSpilled(s.rankedLocked(), s.cold, nil, false)
+		if err != nil {
+			return fmt.Errorf("build rank index: %w", err)
+		}
+		s.cold = &coldTail{}
+		if err := s.cold.setIndex(ix, len(s.scores)); err != nil {
+			return err
+		}
+		s.logger.Printf("keeping the top %d of %d scores in memory, the rest are read from %s", len(s.scores), len(stored), s.indexFile)
+	}
+	s.persistedRev = s.revision
+	return nil
+}
+
+// loadFromIndex reads only the top maxScores entries from the rank index when
+// it matches the scores file. It reports false when the scores file has to be
+// decoded instead.
+func (s *scoreStore) loadFromIndex() bool {
+	data, err := os.Stat(s.filePath)
+	if err != nil {
+		return false
+	}
+	ix, err := openScoreIndex(indexPath(s.filePath), data)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			s.logger.Printf("rebuilding rank index for %s: %v", s.filePath, err)
+		}
+		return false
+	}
+	hot, err := ix.read(0, min(ix.count, s.maxScores))
+	if err != nil {
+		ix.Close()
+		s.logger.Printf("rebuilding rank index for %s: %v", s.filePath, err)
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Get expects the entries in memory in submission order.
+	sort.Slice(hot, func(i, j int) bool { return hot[i].ID < hot[j].ID })
+	s.scores = hot
+	s.nextID = ix.maxID + 1
+	s.markChangedLocked()
+	s.persistedRev = s.revision
+	if ix.count > len(hot) {
+		s.cold = &coldTail{}
+		if err := s.cold.setIndex(ix, len(hot)); err != nil {
+			ix.Close()
+			s.cold = nil
+			s.logger.Printf("rebuilding rank index for %s: %v", s.filePath, err)
+			return false
+		}
+	} else {
+		ix.Close()
+	}
+	s.logger.Printf("loaded the top %d of %d scores from the rank index of %s (next ID: %d)", len(hot), ix.count, s.filePath, s.nextID)
+	return true
+}
+
+// nextIDPath is the file next to the scores file that remembers the next ID
+// once removals may have taken the highest IDs out of the scores file, so
+// they are not handed out again after a restart.
+func nextIDPath(dataPath string) string {
+	return dataPath + ".next-id"
+}
+
+// saveNextID records id as the lowest ID the board may still assign. Callers
+// must hold persistMu.
+func (s *scoreStore) saveNextID(id int) error {
+	if s.filePath == "" {
+		return nil
+	}
+	path, dir := nextIDPath(s.filePath), filepath.Dir(s.filePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, "next-id-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(tmp, "%d\n", id); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		s.logger.Printf("failed to save next ID to %s: %v", path, err)
+		return err
+	}
+	return nil
+}
+
+// restoreNextID raises nextID to the mark saved by saveNextID, if any.
+func (s *scoreStore) restoreNextID() error {
+	if s.filePath == "" {
+		return nil
+	}
+	path := nextIDPath(s.filePath)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var id int
+	if _, err := fmt.Sscan(string(data), &id); err != nil {
+		return fmt.Errorf("read next ID from %s: %w", path, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id > s.nextID {
+		s.logger.Printf("continuing IDs at %d as recorded in %s", id, path)
+		s.nextID = id
+	}
+	return nil
+}
+
+func (s *scoreStore) writeScores(records []Score) error {
+	if s.filePath == "" {
+		return nil
+	}
+	dir := filepath.Dir(s.filePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		s.logger.Printf("failed to create directory %s: %v", dir, err)
+		return err
+	}
+
+	// Get absolute path for logging
+	absPath, _ := filepath.Abs(s.filePath)
+	s.logger.Printf("persisting %d scores to %s (absolute: %s)", len(records), s.filePath, absPath)
+
+	tmp, err := os.CreateTemp(dir, "scores-*.tmp")
+	if err != nil {
+		s.logger.Printf("failed to create temp file in %s: %v", dir, err)
+		return err
+	}
+	tmpPath := tmp.Name()
+	if records == nil {
+		records = []Score{}
+	}
+	if err := encodeScores(tmp, s.format, records); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		s.logger.Printf("failed to encode scores: %v", err)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		s.logger.Printf("failed to sync temp file: %v", err)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		s.logger.Printf("failed to close temp file: %v", err)
+		return err
+	}
+	if err := os.Rename(tmpPath, s.filePath); err != nil {
+		os.Remove(tmpPath)
+		s.logger.Printf("failed to rename temp file to %s: %v", s.filePath, err)
+		return err
+	}
+	s.logger.Printf("successfully persisted scores to %s", s.filePath)
+	return nil
+}
+
+// Scores returns a copy of the scores in rank order.
+func (s *scoreStore) Scores() []Score {
+	var sorted []Score
+	err := s.Each(func(sc Score) error {
+		sorted = append(sorted, sc)
+		return nil
+	})
+	if err != nil {
+		s.logger.Printf("failed to read scores from the rank index: %v", err)
+	}
+	if sorted == nil {
+		sorted = []Score{}
+	}
+	return sorted
+}
+
+// Each walks a copy of the scores in memory followed by a snapshot of the
+// cold tail, so fn runs without holding the lock.
+func (s *scoreStore) Each(fn func(Score) error) error {
+	s.mu.RLock()
+	hot := s.rankedLocked()
+	spilled := s.cold != nil
+	var tail coldTail
+	release := func() {}
+	if spilled {
+		tail, release = s.cold.snapshot()
+	}
+	s.mu.RUnlock()
+	defer release()
+
+	for _, sc := range hot {
+		if err := fn(sc); err != nil {
+			return err
+		}
+	}
+	if !spilled {
+		return nil
+	}
+	return tail.each(fn)
+}
+
+// rankedLocked returns a copy of the scores in memory in rank order.
+func (s *scoreStore) rankedLocked() []Score {
+	sorted := make([]Score, len(s.order))
+	for i, pos := range s.order {
+		sorted[i] = s.scores[pos]
+	}
+	return sorted
+}
+
+// totalLocked counts every score on the board, including the cold tail.
+func (s *scoreStore) totalLocked() int {
+	if s.cold == nil {
+		return len(s.scores)
+	}
+	return len(s.scores) + s.cold.len()
+}
+
+// Get finds id by binary search, since scores are kept in submission order,
+// and falls back to a scan for files whose entries were reordered by hand.
+// The rank is found the same way in the rank order. Scores in the cold tail
+// are found through the ID table of the rank index, without holding the
+// lock.
+func (s *scoreStore) Get(id int) (Placement, bool) {
+	s.mu.RLock()
+	scores, order := s.scores, s.order
+	total := s.totalLocked()
+	if s.cold == nil {
+		s.mu.RUnlock()
+		return findPlacement(scores, order, id, total)
+	}
+	tail, release := s.cold.snapshot()
+	s.mu.RUnlock()
+	defer release()
+
+	if placed, ok := findPlacement(scores, order, id, total); ok {
+		return placed, true
+	}
+	sc, pos, ok, err := tail.find(id)
+	if err != nil {
+		s.logger.Printf("failed to read scores from the rank index: %v", err)
+	}
+	if !ok {
+		return Placement{}, false
+	}
+	return Placement{Score: sc, Rank: len(order) + pos + 1, Total: total}, true
+}
+
+func findPlacement(scores []Score, order []int32, id, total int) (Placement, bool) {
+	pos := sort.Search(len(scores), func(i int) bool { return scores[i].ID >= id })
+	if pos == len(scores) || scores[pos].ID != id {
+		pos = -1
+		for i, sc := range scores {
+			if sc.ID == id {
+				pos = i
+				break
+			}
+		}
+		if pos < 0 {
+			return Placement{}, false
+		}
+	}
+	entry := scores[pos]
+	idx := sort.Search(len(order), func(i int) bool {
+		return !RanksAbove(scores[order[i]], entry)
+	})
+	return Placement{Score: entry, Rank: idx + 1, Total: total}, true
+}
+
+// Page copies one page out of a rank-order snapshot; the lock is only held
+// long enough to take the snapshot, also when the page reaches into the cold
+// tail.
+func (s *scoreStore) Page(page, size int) ScorePage {
+	s.mu.RLock()
+	scores, order := s.scores, s.order
+	total := s.totalLocked()
+	var tail coldTail
+	release := func() {}
+	if s.cold != nil {
+		tail, release = s.cold.snapshot()
+	}
+	s.mu.RUnlock()
+	defer release()
+
+	start, end, resolved, totalPages := PageBounds(total, page, size)
+	hot := len(order)
+	items := make([]Score, 0, end-start)
+	for _, pos := range order[min(start, hot):min(end, hot)] {
+		items = append(items, scores[pos])
+	}
+	if end > hot {
+		cold, err := tail.slice(max(start, hot)-hot, end-hot)
+		if err != nil {
+			s.logger.Printf("failed to read scores from the rank index: %v", err)
+		}
+		items = append(items, cold...)
+	}
+	return ScorePage{
+		Items:      items,
+		FirstRank:  start + 1,
+		Page:       resolved,
+		TotalPages: totalPages,
+		TotalItems: total,
+	}
+}
+
+// markChangedLocked bumps the revision and rebuilds the rank order from
+// scratch. Single additions use insertOrderLocked instead.
+func (s *scoreStore) markChangedLocked() {
+	s.revision++
+	order := make([]int32, len(s.scores))
+	for i := range order {
+		order[i] = int32(i)
+	}
+	scores := s.scores
+	sort.Slice(order, func(i, j int) bool {
+		return RanksAbove(scores[order[i]], scores[order[j]])
+	})
+	s.order = order
+}
+
+// spillLocked moves the scores ranked below maxScores from memory to the
+// front of the cold tail; they rank above everything already there.
+func (s *scoreStore) spillLocked() {
+	if s.maxScores <= 0 || len(s.scores) <= s.maxScores {
+		return
+	}
+	if s.cold == nil {
+		s.cold = &coldTail{}
+	}
+	if len(s.scores) == s.maxScores+1 {
+		// The common case of one new entry: avoid a full re-sort.
+		last := int(s.order[len(s.order)-1])
+		s.cold.pending = append([]Score{s.scores[last]}, s.cold.pending...)
+		s.removeAtLocked(last)
+		return
+	}
+
+	spilled := make([]Score, 0, len(s.scores)-s.maxScores)
+	keep := make([]bool, len(s.scores))
+	for _, pos := range s.order[:s.maxScores] {
+		keep[pos] = true
+	}
+	for _, pos := range s.order[s.maxScores:] {
+		spilled = append(spilled, s.scores[pos])
+	}
+	scores := make([]Score, 0, s.maxScores)
+	for i, sc := range s.scores {
+		if keep[i] {
+			scores = append(scores, sc)
+		}
+	}
+	s.scores = scores
+	s.cold.pending = append(spilled, s.cold.pending...)
+	s.markChangedLocked()
+}
+
+// mergeRanked returns a new slice holding a and b, both in rank order.
+func mergeRanked(a, b []Score) []Score {
+	sort.Slice(b, func(i, j int) bool { return RanksAbove(b[i], b[j]) })
+	merged := make([]Score, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if RanksAbove(b[0], a[0]) {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+func (s *scoreStore) Revision() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.revision
+}
@@ -0,0 +1,272 @@
+package scoreboard_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fishtankhunt/api/server/scoreboard"
+	"fishtankhunt/api/server/scoreboard/storetest"
+)
+
+func TestFileStore(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		interval  time.Duration
+		maxScores int
+	}{
+		{"sync", 0, 0},
+		{"coalesced", time.Millisecond, 0},
+		// A cap below the suite's board sizes keeps most entries in the rank
+		// index.
+		{"capped", 0, 2},
+		{"capped-coalesced", time.Millisecond, 2},
+	} {
+		cfg := scoreboard.DefaultConfig()
+		cfg.PersistInterval = scoreboard.Duration(tc.interval)
+		cfg.MaxScores = tc.maxScores
+		open := func(t *testing.T, path string) scoreboard.Store {
+			store, err := scoreboard.OpenFileStore(path, cfg)
+			if err != nil {
+				t.Fatalf("OpenFileStore: %v", err)
+			}
+			return store
+		}
+		t.Run(tc.name, func(t *testing.T) {
+			storetest.Run(t, func(t *testing.T) scoreboard.Store {
+				return open(t, filepath.Join(t.TempDir(), "scores.json"))
+			})
+			storetest.RunReopen(t, func(t *testing.T) func(t *testing.T) scoreboard.Store {
+				path := filepath.Join(t.TempDir(), "scores.json")
+				return func(t *testing.T) scoreboard.Store { return open(t, path) }
+			})
+		})
+	}
+}
+
+// fillStore adds n scores with random values to an in-memory file store.
+func fillStore(tb testing.TB, n int) scoreboard.Store {
+	tb.Helper()
+	store, err := scoreboard.OpenFileStore("", scoreboard.DefaultConfig())
+	if err != nil {
+		tb.Fatalf("OpenFileStore: %v", err)
+	}
+	tb.Cleanup(func() { store.Close() })
+
+	rng := rand.New(rand.NewSource(1))
+	subs := make([]scoreboard.Submission, n)
+	for i := range subs {
+		subs[i] = scoreboard.Submission{Name: fmt.Sprintf("p%d", i), Score: rng.Intn(100000)}
+	}
+	if _, err := store.AddBatch(subs, time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		tb.Fatalf("AddBatch: %v", err)
+	}
+	return store
+}
+
+func BenchmarkPage(b *testing.B) {
+	const size = 5
+	for _, n := range []int{1_000, 100_000} {
+		store := fillStore(b, n)
+		for _, bc := range []struct {
+			name string
+			page int
+		}{
+			{"first", 1},
+			{"deep", n / size / 2},
+		} {
+			b.Run(fmt.Sprintf("entries=%d/%s", n, bc.name), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					if p := store.Page(bc.page, size); len(p.Items) != size {
+						b.Fatalf("Page(%d, %d) returned %d items", bc.page, size, len(p.Items))
+					}
+				}
+			})
+		}
+	}
+}
+
+// writeBoard writes a scores file of n random entries to a temp directory.
+func writeBoard(b *testing.B, n int) string {
+	b.Helper()
+	path := filepath.Join(b.TempDir(), "scores.json")
+	store, err := scoreboard.OpenFileStore(path, scoreboard.DefaultConfig())
+	if err != nil {
+		b.Fatalf("OpenFileStore: %v", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	subs := make([]scoreboard.Submission, n)
+	for i := range subs {
+		subs[i] = scoreboard.Submission{Name: fmt.Sprintf("p%d", i), Score: rng.Intn(100000)}
+	}
+	if _, err := store.AddBatch(subs, time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)); err != nil {
+		b.Fatalf("AddBatch: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		b.Fatalf("Close: %v", err)
+	}
+	return path
+}
+
+func quietLog(b *testing.B) {
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+}
+
+// BenchmarkOpenFileStore measures startup. With a cap, only the top entries
+// are read from the rank index written by the first open.
+func BenchmarkOpenFileStore(b *testing.B) {
+	quietLog(b)
+	for _, n := range []int{100_000, 1_000_000} {
+		path := writeBoard(b, n)
+		for _, maxScores := range []int{0, 10_000} {
+			b.Run(fmt.Sprintf("entries=%d/max-scores=%d", n, maxScores), func(b *testing.B) {
+				cfg := scoreboard.DefaultConfig()
+				cfg.MaxScores = maxScores
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					store, err := scoreboard.OpenFileStore(path, cfg)
+					if err != nil {
+						b.Fatalf("OpenFileStore: %v", err)
+					}
+					store.Close()
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkAdd measures submissions without disk writes, which
+// PersistInterval takes off the request path.
+func BenchmarkAdd(b *testing.B) {
+	quietLog(b)
+	for _, n := range []int{100_000, 1_000_000} {
+		path := writeBoard(b, n)
+		for _, maxScores := range []int{0, 10_000} {
+			b.Run(fmt.Sprintf("entries=%d/max-scores=%d", n, maxScores), func(b *testing.B) {
+				cfg := scoreboard.DefaultConfig()
+				cfg.MaxScores = maxScores
+				cfg.PersistInterval = scoreboard.Duration(time.Hour)
+				store, err := scoreboard.OpenFileStore(path, cfg)
+				if err != nil {
+					b.Fatalf("OpenFileStore: %v", err)
+				}
+				rng := rand.New(rand.NewSource(2))
+				at := time.Date(2024, time.March, 2, 12, 0, 0, 0, time.UTC)
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := store.Add(scoreboard.Submission{Name: "bench", Score: rng.Intn(100000)}, at); err != nil {
+						b.Fatalf("Add: %v", err)
+					}
+				}
+				b.StopTimer()
+				store.Close()
+			})
+		}
+	}
+}
+
+// BenchmarkPageColdTail reads a deep page of a capped board, which comes from
+// the rank index.
+func BenchmarkPageColdTail(b *testing.B) {
+	quietLog(b)
+	const size = 5
+	for _, n := range []int{100_000, 1_000_000} {
+		path := writeBoard(b, n)
+		cfg := scoreboard.DefaultConfig()
+		cfg.MaxScores = 10_000
+		store, err := scoreboard.OpenFileStore(path, cfg)
+		if err != nil {
+			b.Fatalf("OpenFileStore: %v", err)
+		}
+		b.Cleanup(func() { store.Close() })
+		b.Run(fmt.Sprintf("entries=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if p := store.Page(n/size/2, size); len(p.Items) != size {
+					b.Fatalf("deep page returned %d items", len(p.Items))
+				}
+			}
+		})
+	}
+}
+
+func TestFileStoreReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scores.json")
+	store, err := scoreboard.OpenFileStore(path, scoreboard.DefaultConfig())
+	if err != nil {
+		t.Fatalf("OpenFileStore: %v", err)
+	}
+	if _, err := store.Add(scoreboard.Submission{Name: "kept", Score: 10}, time.Now()); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := scoreboard.DefaultConfig()
+	cfg.ReadOnly = true
+	store, err = scoreboard.OpenFileStore(path, cfg)
+	if err != nil {
+		t.Fatalf("OpenFileStore read-only: %v", err)
+	}
+	if _, err := store.Add(scoreboard.Submission{Name: "new", Score: 20}, time.Now()); !errors.Is(err, scoreboard.ErrReadOnly) {
+		t.Errorf("Add = %v, want ErrReadOnly", err)
+	}
+	if _, err := store.AddBatch([]scoreboard.Submission{{Name: "new", Score: 20}}, time.Now()); !errors.Is(err, scoreboard.ErrReadOnly) {
+		t.Errorf("AddBatch = %v, want ErrReadOnly", err)
+	}
+	if _, err := store.Remove(func(scoreboard.Score) bool { return true }); !errors.Is(err, scoreboard.ErrReadOnly) {
+		t.Errorf("Remove = %v, want ErrReadOnly", err)
+	}
+	if err := store.(interface{ Rewrite() error }).Rewrite(); !errors.Is(err, scoreboard.ErrReadOnly) {
+		t.Errorf("Rewrite = %v, want ErrReadOnly", err)
+	}
+	if got := store.Page(1, 5).TotalItems; got != 1 {
+		t.Errorf("read-only store holds %d scores, want 1", got)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(before, after) {
+		t.Errorf("read-only store changed the scores file")
+	}
+}
+
+// BenchmarkGetColdTail looks up a score deep in the cold tail of a capped
+// board, which goes through the ID table of the rank index.
+func BenchmarkGetColdTail(b *testing.B) {
+	quietLog(b)
+	for _, n := range []int{100_000, 1_000_000} {
+		path := writeBoard(b, n)
+		cfg := scoreboard.DefaultConfig()
+		cfg.MaxScores = 10_000
+		store, err := scoreboard.OpenFileStore(path, cfg)
+		if err != nil {
+			b.Fatalf("OpenFileStore: %v", err)
+		}
+		b.Cleanup(func() { store.Close() })
+		last := store.Page(n, 1).Items[0].ID
+		b.Run(fmt.Sprintf("entries=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if placed, ok := store.Get(last); !ok || placed.Rank != n {
+					b.Fatalf("Get(%d) = rank %d, %v; want rank %d", last, placed.Rank, ok, n)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+// Package storetest is a conformance suite for scoreboard.Store
+// implementations. Every backend must pass it so ranking and pagination look
+// the same to clients whichever store a server runs on:
+//
+//	func TestStore(t *testing.T) {
+//		storetest.Run(t, func(t *testing.T) scoreboard.Store {
+//			return memstore.New()
+//		})
+//	}
+//
+// Backends that keep scores across restarts also pass RunReopen.
+package storetest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"fishtankhunt/api/server/scoreboard"
+)
+
+// base is the timestamp of the first submission in every test; later ones are
+// offset from it so that ties on CreatedAt are deliberate.
+var base = time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+
+// Run runs the suite as subtests of t. newStore must return an empty store
+// that nothing else uses; Run closes it when the subtest ends.
+func Run(t *testing.T, newStore func(t *testing.T) scoreboard.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s scoreboard.Store)
+	}{
+		{"Empty", testEmpty},
+		{"AddAssignsIDs", testAddAssignsIDs},
+		{"Ranking", testRanking},
+		{"Pagination", testPagination},
+		{"Get", testGet},
+		{"AddBatch", testAddBatch},
+		{"Remove", testRemove},
+		{"ScoresIsACopy", testScoresIsACopy},
+		{"Each", testEach},
+		{"ConcurrentAdds", testConcurrentAdds},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			t.Cleanup(func() {
+				if err := s.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			})
+			tt.fn(t, s)
+		})
+	}
+}
+
+// RunReopen runs the cases that close a store and open another one over the
+// same data. newBoard must set up empty storage that nothing else uses and
+// return a function that opens a store on it. Every store that is opened is
+// closed before the next one opens.
+func RunReopen(t *testing.T, newBoard func(t *testing.T) (open func(t *testing.T) scoreboard.Store)) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, open func(t *testing.T) scoreboard.Store)
+	}{
+		{"ScoresSurvive", testScoresSurvive},
+		{"IDsNotReusedAfterRemove", testIDsNotReusedAfterRemove},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newBoard(t))
+		})
+	}
+}
+
+func testEmpty(t *testing.T, s scoreboard.Store) {
+	p := s.Page(1, 5)
+	if len(p.Items) != 0 || p.TotalItems != 0 || p.Page != 1 || p.TotalPages != 1 {
+		t.Errorf("Page(1, 5) on empty store = %+v, want no items on page 1 of 1", p)
+	}
+	if got := s.Scores(); len(got) != 0 {
+		t.Errorf("Scores() on empty store returned %d entries", len(got))
+	}
+}
+
+func testAddAssignsIDs(t *testing.T, s scoreboard.Store) {
+	before := s.Revision()
+	for i := 1; i <= 3; i++ {
+		at := base.Add(time.Duration(i) * time.Second)
+		placed := mustAdd(t, s, fmt.Sprintf("p%d", i), 100*i, at)
+		if placed.Score.ID != i {
+			t.Errorf("submission %d got ID %d", i, placed.Score.ID)
+		}
+		if !placed.Score.CreatedAt.Equal(at) {
+			t.Errorf("submission %d CreatedAt = %v, want %v", i, placed.Score.CreatedAt, at)
+		}
+		if placed.Total != i {
+			t.Errorf("submission %d Total = %d, want %d", i, placed.Total, i)
+		}
+		if placed.Rank != 1 {
+			t.Errorf("submission %d with the highest score ranked %d", i, placed.Rank)
+		}
+	}
+	if s.Revision() <= before {
+		t.Errorf("Revision did not increase after Add")
+	}
+}
+
+func testRanking(t *testing.T, s scoreboard.Store) {
+	mustAdd(t, s, "late-high", 500, base.Add(2*time.Second))
+	mustAdd(t, s, "low", 100, base)
+	mustAdd(t, s, "early-high", 500, base.Add(time.Second))
+	// Same score and timestamp as early-high: the lower ID wins the tie.
+	placed := mustAdd(t, s, "tie", 500, base.Add(time.Second))
+	if placed.Rank != 2 {
+		t.Errorf("tie ranked %d, want 2", placed.Rank)
+	}
+
+	want := []string{"early-high", "tie", "late-high", "low"}
+	checkNames(t, "Scores()", s.Scores(), want)
+	checkNames(t, "Page(1, 10).Items", s.Page(1, 10).Items, want)
+}
+
+func testPagination(t *testing.T, s scoreboard.Store) {
+	for i := 0; i < 12; i++ {
+		mustAdd(t, s, fmt.Sprintf("p%02d", i), 1000-i, base.Add(time.Duration(i)*time.Second))
+	}
+	tests := []struct {
+		page, size                 int
+		wantPage, wantFirst, items int
+	}{
+		{1, 5, 1, 1, 5},
+		{2, 5, 2, 6, 5},
+		{3, 5, 3, 11, 2},
+		{0, 5, 1, 1, 5},
+		{-4, 5, 1, 1, 5},
+		{99, 5, 3, 11, 2},
+		{1, 0, 1, 1, 5},
+		{1, 50, 1, 1, 12},
+		{1, math.MaxInt, 1, 1, 12},
+		{3, math.MaxInt, 1, 1, 12},
+	}
+	for _, tt := range tests {
+		p := s.Page(tt.page, tt.size)
+		if p.Page != tt.wantPage || p.FirstRank != tt.wantFirst || len(p.Items) != tt.items || p.TotalItems != 12 {
+			t.Errorf("Page(%d, %d) = page %d, first rank %d, %d items, %d total; want page %d, first rank %d, %d items, 12 total",
+				tt.page, tt.size, p.Page, p.FirstRank, len(p.Items), p.TotalItems, tt.wantPage, tt.wantFirst, tt.items)
+			continue
+		}
+		for i, sc := range p.Items {
+			if want := fmt.Sprintf("p%02d", p.FirstRank+i-1); sc.Name != want {
+				t.Errorf("Page(%d, %d) item %d = %s, want %s", tt.page, tt.size, i, sc.Name, want)
+			}
+		}
+	}
+	if got := s.Page(1, 5).TotalPages; got != 3 {
+		t.Errorf("TotalPages = %d, want 3", got)
+	}
+}
+
+func testGet(t *testing.T, s scoreboard.Store) {
+	mustAdd(t, s, "mid", 50, base)
+	mustAdd(t, s, "top", 90, base.Add(time.Second))
+	mustAdd(t, s, "low", 10, base.Add(2*time.Second))
+	mustAdd(t, s, "mid-tie", 50, base)
+
+	for _, want := range []struct {
+		id   int
+		name string
+		rank int
+	}{{1, "mid", 2}, {2, "top", 1}, {3, "low", 4}, {4, "mid-tie", 3}} {
+		got, ok := s.Get(want.id)
+		if !ok {
+			t.Errorf("Get(%d) found nothing", want.id)
+			continue
+		}
+		if got.Score.Name != want.name || got.Rank != want.rank || got.Total != 4 {
+			t.Errorf("Get(%d) = %s ranked %d of %d, want %s ranked %d of 4",
+				want.id, got.Score.Name, got.Rank, got.Total, want.name, want.rank)
+		}
+	}
+	if _, ok := s.Get(99); ok {
+		t.Errorf("Get(99) found a score that was never added")
+	}
+
+	if _, err := s.Remove(func(sc scoreboard.Score) bool { return sc.ID == 2 }); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, ok := s.Get(2); ok {
+		t.Errorf("Get(2) still finds a removed score")
+	}
+	if got, _ := s.Get(3); got.Rank != 3 || got.Total != 3 {
+		t.Errorf("Get(3) after removal ranked %d of %d, want 3 of 3", got.Rank, got.Total)
+	}
+}
+
+func testAddBatch(t *testing.T, s scoreboard.Store) {
+	mustAdd(t, s, "first", 50, base)
+	added, err := s.AddBatch([]scoreboard.Submission{
+		{Name: "b1", Score: 10},
+		{Name: "b2", Score: 90},
+		{Name: "b3", Score: 30},
+	}, base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("AddBatch: %v", err)
+	}
+	if len(added) != 3 {
+		t.Fatalf("AddBatch returned %d scores, want 3", len(added))
+	}
+	for i, sc := range added {
+		if sc.ID != i+2 {
+			t.Errorf("batch entry %d got ID %d, want %d", i, sc.ID, i+2)
+		}
+	}
+	checkNames(t, "Scores()", s.Scores(), []string{"b2", "first", "b3", "b1"})
+}
+
+func testRemove(t *testing.T, s scoreboard.Store) {
+	for i := 0; i < 5; i++ {
+		mustAdd(t, s, fmt.Sprintf("p%d", i), 10*i, base.Add(time.Duration(i)*time.Second))
+	}
+	before := s.Revision()
+	removed, err := s.Remove(func(sc scoreboard.Score) bool { return sc.Score%20 == 0 })
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if removed != 3 {
+		t.Errorf("Remove reported %d, want 3", removed)
+	}
+	if s.Revision() <= before {
+		t.Errorf("Revision did not increase after Remove")
+	}
+	checkNames(t, "Scores()", s.Scores(), []string{"p3", "p1"})
+
+	placed := mustAdd(t, s, "next", 15, base.Add(time.Minute))
+	if placed.Score.ID != 6 {
+		t.Errorf("ID after Remove = %d, want 6", placed.Score.ID)
+	}
+	if placed.Rank != 2 || placed.Total != 3 {
+		t.Errorf("placement after Remove = rank %d of %d, want 2 of 3", placed.Rank, placed.Total)
+	}
+}
+
+func testScoresIsACopy(t *testing.T, s scoreboard.Store) {
+	mustAdd(t, s, "keep", 10, base)
+	got := s.Scores()
+	got[0].Name = "changed"
+	if name := s.Scores()[0].Name; name != "keep" {
+		t.Errorf("modifying the Scores() result changed the store: name is %q", name)
+	}
+	page := s.Page(1, 5)
+	page.Items[0].Name = "changed"
+	if name := s.Page(1, 5).Items[0].Name; name != "keep" {
+		t.Errorf("modifying Page items changed the store: name is %q", name)
+	}
+}
+
+func testEach(t *testing.T, s scoreboard.Store) {
+	for i := 0; i < 6; i++ {
+		mustAdd(t, s, fmt.Sprintf("p%d", i), (i*37)%11, base.Add(time.Duration(i)*time.Second))
+	}
+	var got []scoreboard.Score
+	if err := s.Each(func(sc scoreboard.Score) error {
+		got = append(got, sc)
+		return nil
+	}); err != nil {
+		t.Fatalf("Each: %v", err)
+	}
+	want := s.Scores()
+	names := make([]string, len(want))
+	for i, sc := range want {
+		names[i] = sc.Name
+	}
+	checkNames(t, "Each", got, names)
+
+	stop := errors.New("stop")
+	calls := 0
+	err := s.Each(func(scoreboard.Score) error {
+		calls++
+		if calls == 2 {
+			return stop
+		}
+		return nil
+	})
+	if !errors.Is(err, stop) || calls != 2 {
+		t.Errorf("Each after fn failed = %v with %d calls, want the error after 2 calls", err, calls)
+	}
+}
+
+func testConcurrentAdds(t *testing.T, s scoreboard.Store) {
+	const workers, each = 8, 25
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < each; i++ {
+				sub := scoreboard.Submission{Name: fmt.Sprintf("w%d-%d", w, i), Score: (w*each + i) * 7 % 101}
+				if _, err := s.Add(sub, base.Add(time.Duration(i)*time.Millisecond)); err != nil {
+					t.Errorf("Add: %v", err)
+				}
+				s.Page(1, 5)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	all := s.Scores()
+	if len(all) != workers*each {
+		t.Fatalf("stored %d scores, want %d", len(all), workers*each)
+	}
+	seen := make(map[int]bool, len(all))
+	for i, sc := range all {
+		if seen[sc.ID] {
+			t.Errorf("ID %d assigned twice", sc.ID)
+		}
+		seen[sc.ID] = true
+		if i > 0 && scoreboard.RanksAbove(sc, all[i-1]) {
+			t.Errorf("scores out of order at rank %d", i+1)
+		}
+	}
+}
+
+func testScoresSurvive(t *testing.T, open func(t *testing.T) scoreboard.Store) {
+	s := open(t)
+	mustAdd(t, s, "low", 10, base)
+	mustAdd(t, s, "high", 90, base.Add(time.Second))
+	mustAdd(t, s, "mid", 50, base.Add(2*time.Second))
+	mustClose(t, s)
+
+	s = open(t)
+	checkNames(t, "Scores() after reopening", s.Scores(), []string{"high", "mid", "low"})
+	if got, ok := s.Get(3); !ok || got.Rank != 2 || got.Total != 3 {
+		t.Errorf("Get(3) after reopening = rank %d of %d, %v; want rank 2 of 3", got.Rank, got.Total, ok)
+	}
+	if placed := mustAdd(t, s, "next", 5, base.Add(time.Minute)); placed.Score.ID != 4 {
+		t.Errorf("ID after reopening = %d, want 4", placed.Score.ID)
+	}
+	mustClose(t, s)
+}
+
+// testIDsNotReusedAfterRemove removes the newest scores, so their IDs are no
+// longer in the stored data, and checks that a restart does not hand them
+// out again.
+func testIDsNotReusedAfterRemove(t *testing.T, open func(t *testing.T) scoreboard.Store) {
+	s := open(t)
+	for i := 1; i <= 3; i++ {
+		mustAdd(t, s, fmt.Sprintf("p%d", i), 10*i, base.Add(time.Duration(i)*time.Second))
+	}
+	if _, err := s.Remove(func(sc scoreboard.Score) bool { return sc.ID >= 2 }); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	mustClose(t, s)
+
+	s = open(t)
+	if placed := mustAdd(t, s, "next", 5, base.Add(time.Minute)); placed.Score.ID != 4 {
+		t.Errorf("ID after Remove and reopening = %d, want 4", placed.Score.ID)
+	}
+	mustClose(t, s)
+
+	// Removing everything must not reset the IDs either.
+	s = open(t)
+	if _, err := s.Remove(func(scoreboard.Score) bool { return true }); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	mustClose(t, s)
+	s = open(t)
+	if placed := mustAdd(t, s, "after-clear", 5, base.Add(time.Hour)); placed.Score.ID != 5 {
+		t.Errorf("ID after removing everything and reopening = %d, want 5", placed.Score.ID)
+	}
+	mustClose(t, s)
+}
+
+func mustClose(t *testing.T, s scoreboard.Store) {
+	t.Helper()
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+}
+
+func mustAdd(t *testing.T, s scoreboard.Store, name string, score int, at time.Time) scoreboard.Placement {
+	t.Helper()
+	placed, err := s.Add(scoreboard.Submission{Name: name, Score: score}, at)
+	if err != nil {
+		t.Fatalf("Add(%s): %v", name, err)
+	}
+	return placed
+}
+
+func checkNames(t *testing.T, what string, got []scoreboard.Score, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s has %d entries, want %d", what, len(got), len(want))
+		return
+	}
+	for i := range want {
+		if got[i].Name != want[i] {
+			t.Errorf("%s[%d] = %s, want %s", what, i, got[i].Name, want[i])
+		}
+	}
+}
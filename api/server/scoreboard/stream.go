@@ -0,0 +1,74 @@
+package scoreboard
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+)
+
+const (
+	// streamThreshold is the page size above which GET /scores streams items
+	// instead of encoding the whole response in memory.
+	streamThreshold = 100
+	// streamFlushEvery controls how many items are written between flushes.
+	// It stays below streamThreshold so every streamed page is flushed at
+	// least once before it ends.
+	streamFlushEvery = 50
+)
+
+// streamPage writes a scoresResponse one item at a time. The metadata fields
+// come first and are flushed straight away so clients can size their UI
+// before the items arrive.
+func (h *scoreHandler) streamPage(w http.ResponseWriter, r *http.Request, page, size int) {
+	p := h.listPage(r, page, size)
+	header := struct {
+		Page       int `json:"page"`
+		Size       int `json:"size"`
+		TotalItems int `json:"totalItems"`
+		TotalPages int `json:"totalPages"`
+	}{p.Page, size, p.TotalItems, p.TotalPages}
+
+	head, err := json.Marshal(header)
+	if err != nil {
+		h.logger.Printf("error encoding response: %v", err)
+		http.Error(w, "failed to encode response", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	bw := bufio.NewWriter(w)
+	// Replace the closing brace of the header object with the items array.
+	bw.Write(head[:len(head)-1])
+	bw.WriteString(`,"items":[`)
+	if err := bw.Flush(); err != nil {
+		h.logger.Printf("error streaming response: %v", err)
+		return
+	}
+	rc.Flush()
+
+	for i, entry := range p.Items {
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		item, err := json.Marshal(newScoreListItem(entry, p.FirstRank+i))
+		if err != nil {
+			h.logger.Printf("error streaming response: %v", err)
+			return
+		}
+		bw.Write(item)
+		if (i+1)%streamFlushEvery == 0 {
+			if err := bw.Flush(); err != nil {
+				h.logger.Printf("error streaming response: %v", err)
+				return
+			}
+			rc.Flush()
+		}
+	}
+	bw.WriteString("]}\n")
+	if err := bw.Flush(); err != nil {
+		h.logger.Printf("error streaming response: %v", err)
+	}
+}
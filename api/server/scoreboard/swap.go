@@ -0,0 +1,187 @@
+package scoreboard
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var (
+	errUnknownBoard    = errors.New("unknown board")
+	errDataFileActive  = errors.New("data file is already active")
+	errInvalidDataFile = errors.New("invalid data file")
+)
+
+// swapStore is a Store whose backing store can be replaced while the server
+// runs. Every operation holds mu for reading, so a swap waits for in-flight
+// operations and nothing reaches a store once it has been swapped out.
+type swapStore struct {
+	mu      sync.RWMutex
+	current Store
+	// path is the data file behind current, or "" for injected and in-memory
+	// stores.
+	path string
+	// offset keeps Revision increasing across swaps, so cached pages of the
+	// previous store are never served for the new one.
+	offset uint64
+}
+
+func newSwapStore(store Store, path string) *swapStore {
+	return &swapStore{current: store, path: path}
+}
+
+func (s *swapStore) Add(sub Submission, at time.Time) (Placement, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Add(sub, at)
+}
+
+func (s *swapStore) AddBatch(subs []Submission, at time.Time) ([]Score, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.AddBatch(subs, at)
+}
+
+func (s *swapStore) Remove(drop func(Score) bool) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Remove(drop)
+}
+
+func (s *swapStore) Get(id int) (Placement, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Get(id)
+}
+
+func (s *swapStore) Page(page, size int) ScorePage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Page(page, size)
+}
+
+func (s *swapStore) Scores() []Score {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Scores()
+}
+
+func (s *swapStore) Each(fn func(Score) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Each(fn)
+}
+
+func (s *swapStore) Revision() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.offset + s.current.Revision()
+}
+
+func (s *swapStore) Close() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.current.Close()
+}
+
+// Rewrite rewrites the current data file; stores without one cannot be
+// rewritten.
+func (s *swapStore) Rewrite() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	rw, ok := s.current.(interface{ Rewrite() error })
+	if !ok || s.path == "" {
+		return errors.New("store has no scores file to rewrite")
+	}
+	return rw.Rewrite()
+}
+
+func (s *swapStore) dataPath() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.path
+}
+
+// swap installs next and returns the store it replaced, which the caller
+// must close.
+func (s *swapStore) swap(next Store, path string) (Store, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	prev, prevPath := s.current, s.path
+	s.offset += prev.Revision() + 1
+	s.current, s.path = next, path
+	return prev, prevPath
+}
+
+// SwapDataFile makes path the data file of board ("" for the default board)
+// without restarting. The file is loaded in full first, and the board keeps
+// its current file if that fails. With create, a missing file starts an empty
+// board. The previous file gets its pending scores flushed and is otherwise
+// left untouched. A file that any board is serving, including board itself,
+// is refused: two stores writing one file would overwrite each other's
+// scores. Read-only servers refuse every swap. SwapDataFile reports how many
+// scores the new file holds.
+func (s *Server) SwapDataFile(board, path string, create bool) (int, error) {
+	if s.cfg.ReadOnly {
+		return 0, ErrReadOnly
+	}
+	target := s.store
+	if board != "" {
+		var ok bool
+		if target, ok = s.boards[board]; !ok {
+			return 0, fmt.Errorf("%w %q", errUnknownBoard, board)
+		}
+	}
+
+	s.swapMu.Lock()
+	defer s.swapMu.Unlock()
+
+	if name, ok := s.boardServing(path); ok {
+		owner := "the default board"
+		if name != "" {
+			owner = fmt.Sprintf("board %q", name)
+		}
+		return 0, fmt.Errorf("%w: %s is served by %s", errDataFileActive, path, owner)
+	}
+	if _, err := os.Stat(path); err != nil && !(create && errors.Is(err, os.ErrNotExist)) {
+		return 0, fmt.Errorf("%w: %v", errInvalidDataFile, err)
+	}
+	next, err := newScoreStore(path, s.cfg.storeOptions(s.logger))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", errInvalidDataFile, err)
+	}
+
+	prev, prevPath := target.swap(next, path)
+	total := next.Page(1, 1).TotalItems
+	if prevPath == "" {
+		prevPath = "in-memory store"
+	}
+	s.logger.Printf("board %q now serves %s (%d scores), replacing %s", board, path, total, prevPath)
+	if err := prev.Close(); err != nil {
+		s.logger.Printf("failed to close previous data file %s: %v", prevPath, err)
+	}
+	return total, nil
+}
+
+// boardServing returns the board whose data file is path ("" for the default
+// board). Callers hold swapMu, so no board changes file meanwhile.
+func (s *Server) boardServing(path string) (string, bool) {
+	if current := s.store.dataPath(); current != "" && samePath(current, path) {
+		return "", true
+	}
+	for name, b := range s.boards {
+		if current := b.dataPath(); current != "" && samePath(current, path) {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -0,0 +1,155 @@
+package scoreboard
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// apiKeyHeader carries the tenant API key. Requests without it are served
+// from the default board, which is what the bundled frontend uses.
+const apiKeyHeader = "X-API-Key"
+
+// Tenant is a named API key bound to its own board and rate limit, so several
+// games can share one server without seeing or affecting each other's scores.
+type Tenant struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+	// RateLimit is the sustained number of requests per second allowed for
+	// the key; 0 means unlimited. Burst is how many requests may arrive at
+	// once and defaults to RateLimit rounded up.
+	RateLimit float64 `json:"rateLimit,omitempty"`
+	Burst     int     `json:"burst,omitempty"`
+}
+
+func validateTenants(tenants []Tenant) error {
+	names := make(map[string]bool, len(tenants))
+	keys := make(map[string]bool, len(tenants))
+	for _, t := range tenants {
+		switch {
+		case !validTenantName(t.Name):
+			return fmt.Errorf("tenant name %q must be letters, digits, '-' or '_'", t.Name)
+		case names[t.Name]:
+			return fmt.Errorf("duplicate tenant name %q", t.Name)
+		case t.Key == "":
+			return fmt.Errorf("tenant %s has no key", t.Name)
+		case keys[t.Key]:
+			return fmt.Errorf("tenant %s reuses another tenant's key", t.Name)
+		case t.RateLimit < 0 || t.Burst < 0:
+			return fmt.Errorf("tenant %s: rateLimit and burst must not be negative", t.Name)
+		}
+		names[t.Name] = true
+		keys[t.Key] = true
+	}
+	return nil
+}
+
+func validTenantName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// TenantDataPath returns the scores file of the tenant called name: a file
+// with the same base name as dataPath under tenants/<name>/ next to it.
+func TenantDataPath(dataPath, name string) string {
+	return filepath.Join(filepath.Dir(dataPath), "tenants", name, filepath.Base(dataPath))
+}
+
+type tenantBoard struct {
+	name    string
+	handler http.Handler
+	// limiter is nil for tenants without a rate limit.
+	limiter *tokenBucket
+}
+
+// tenantRouter sends each request to the board of its API key, or to
+// fallback when no key is given.
+type tenantRouter struct {
+	fallback       http.Handler
+	boards         map[string]*tenantBoard
+	allowedOrigins []string
+	readOnly       bool
+}
+
+func (t *tenantRouter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		t.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	board, ok := t.boards[key]
+	if !ok {
+		setCORSHeaders(w, r, t.allowedOrigins, t.readOnly)
+		http.Error(w, "unknown API key", http.StatusUnauthorized)
+		return
+	}
+	if board.limiter != nil {
+		if wait, ok := board.limiter.take(); !ok {
+			setCORSHeaders(w, r, t.allowedOrigins, t.readOnly)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "rate limit exceeded for "+board.name, http.StatusTooManyRequests)
+			return
+		}
+	}
+	board.handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), boardKey{}, board.name)))
+}
+
+// tokenBucket allows rate requests per second with bursts of up to burst.
+type tokenBucket struct {
+	rate  float64
+	burst float64
+	clock Clock
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// newTokenBucket returns nil when rate is not positive, meaning unlimited.
+func newTokenBucket(rate float64, burst int, clock Clock) *tokenBucket {
+	if rate <= 0 {
+		return nil
+	}
+	if burst <= 0 {
+		burst = int(math.Ceil(rate))
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		clock:  clock,
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// take consumes one token. When none is left it reports how long until the
+// next one becomes available.
+func (b *tokenBucket) take() (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.clock.Now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(b.burst, b.tokens+elapsed.Seconds()*b.rate)
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second)), false
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type simOp int
+
+const (
+	simSubmit simOp = iota
+	simRead
+)
+
+func (op simOp) String() string {
+	if op == simSubmit {
+		return "POST /scores"
+	}
+	return "GET /scores"
+}
+
+type simResult struct {
+	op      simOp
+	latency time.Duration
+	err     error
+}
+
+// runSimulate drives synthetic submission and read traffic against a running
+// instance and reports latency percentiles per operation.
+func runSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	target := fs.String("target", "http://localhost:8090", "base URL of the scoreboard to load (submissions are stored there)")
+	duration := fs.Duration("duration", 10*time.Second, "how long to generate traffic")
+	concurrency := fs.Int("concurrency", 8, "number of concurrent workers")
+	submitRate := fs.Float64("submit-rate", 5, "score submissions per second (0 sends none)")
+	readRate := fs.Float64("read-rate", 50, "leaderboard reads per second (0 sends none)")
+	pageSize := fs.Int("page-size", 5, "page size requested by reads")
+	maxPage := fs.Int("max-page", 3, "reads pick a page between 1 and max-page")
+	fs.Parse(args)
+
+	if *concurrency <= 0 || *maxPage <= 0 {
+		log.Fatalf("concurrency and max-page must be positive")
+	}
+	intervals := map[simOp]time.Duration{}
+	for _, r := range []struct {
+		flag string
+		op   simOp
+		rate float64
+	}{{"submit-rate", simSubmit, *submitRate}, {"read-rate", simRead, *readRate}} {
+		interval, ok := tickInterval(r.rate)
+		if !ok {
+			log.Fatalf("%s must be 0 or between %.3g and %d per second, got %v", r.flag, float64(time.Second)/math.MaxInt64, time.Second, r.rate)
+		}
+		intervals[r.op] = interval
+	}
+	if *submitRate == 0 && *readRate == 0 {
+		log.Fatalf("submit-rate and read-rate are both 0, so there is nothing to simulate")
+	}
+
+	base := strings.TrimRight(*target, "/")
+	client := &http.Client{Timeout: 10 * time.Second}
+	jobs := make(chan simOp, *concurrency*4)
+	results := make(chan simResult, *concurrency*4)
+
+	var workers sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		workers.Add(1)
+		go func(seed int64) {
+			defer workers.Done()
+			rng := rand.New(rand.NewSource(seed))
+			for op := range jobs {
+				start := time.Now()
+				var err error
+				if op == simSubmit {
+					err = simulateSubmit(client, base, rng)
+				} else {
+					err = simulateRead(client, base, 1+rng.Intn(*maxPage), *pageSize)
+				}
+				results <- simResult{op: op, latency: time.Since(start), err: err}
+			}
+		}(time.Now().UnixNano() + int64(i))
+	}
+
+	latencies := map[simOp][]time.Duration{}
+	failures := map[simOp]int{}
+	collected := make(chan struct{})
+	go func() {
+		defer close(collected)
+		for res := range results {
+			if res.err != nil {
+				failures[res.op]++
+				continue
+			}
+			latencies[res.op] = append(latencies[res.op], res.latency)
+		}
+	}()
+
+	log.Printf("simulating %.1f submits/s and %.1f reads/s against %s for %s with %d workers",
+		*submitRate, *readRate, base, *duration, *concurrency)
+
+	var producers sync.WaitGroup
+	var dropMu sync.Mutex
+	dropped := map[simOp]int{}
+	deadline := time.After(*duration)
+	stop := make(chan struct{})
+	for op, interval := range intervals {
+		if interval == 0 {
+			continue
+		}
+		producers.Add(1)
+		go func(op simOp, interval time.Duration) {
+			defer producers.Done()
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ticker.C:
+					select {
+					case jobs <- op:
+					default:
+						dropMu.Lock()
+						dropped[op]++
+						dropMu.Unlock()
+					}
+				case <-stop:
+					return
+				}
+			}
+		}(op, interval)
+	}
+
+	<-deadline
+	close(stop)
+	producers.Wait()
+	close(jobs)
+	workers.Wait()
+	close(results)
+	<-collected
+
+	fmt.Printf("%-13s %7s %7s %7s %9s %9s %9s %9s\n", "operation", "ok", "failed", "dropped", "p50", "p90", "p99", "max")
+	for _, op := range []simOp{simSubmit, simRead} {
+		lat := latencies[op]
+		sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+		fmt.Printf("%-13s %7d %7d %7d %9s %9s %9s %9s\n", op, len(lat), failures[op], dropped[op],
+			percentile(lat, 50), percentile(lat, 90), percentile(lat, 99), percentile(lat, 100))
+	}
+}
+
+// percentile returns the p-th percentile of sorted latencies, rounded for
+// display.
+// tickInterval converts a rate per second into the interval between
+// operations. A rate of 0 gives 0, meaning none are sent; rates whose
+// interval does not fit a time.Duration, or rounds down to zero, are refused.
+func tickInterval(rate float64) (time.Duration, bool) {
+	if rate == 0 {
+		return 0, true
+	}
+	if !(rate > 0) {
+		return 0, false
+	}
+	interval := float64(time.Second) / rate
+	if interval >= math.MaxInt64 || time.Duration(interval) <= 0 {
+		return 0, false
+	}
+	return time.Duration(interval), true
+}
+
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := (len(sorted)*p+99)/100 - 1
+	if idx < 0 {
+		idx = 0
+	}
+	return sorted[idx].Round(10 * time.Microsecond)
+}
+
+func simulateSubmit(client *http.Client, base string, rng *rand.Rand) error {
+	body, err := json.Marshal(map[string]any{
+		"name":        fmt.Sprintf("SIM%03d", rng.Intn(1000)),
+		"score":       rng.Intn(300000),
+		"timeSeconds": rng.Intn(120),
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Post(base+"/scores", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	return drainResponse(resp, http.StatusCreated)
+}
+
+func simulateRead(client *http.Client, base string, page, size int) error {
+	resp, err := client.Get(fmt.Sprintf("%s/scores?page=%d&size=%d", base, page, size))
+	if err != nil {
+		return err
+	}
+	return drainResponse(resp, http.StatusOK)
+}
+
+func drainResponse(resp *http.Response, want int) error {
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return err
+	}
+	if resp.StatusCode != want {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}